@@ -0,0 +1,205 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// doJSON sends an authenticated request with body marshaled as JSON (when non-nil)
+// and decodes the response envelope into res. name and action identify the
+// request as described by withOperation.
+func (c *Cloudflare) doJSON(ctx context.Context, method string, requestURL string, body interface{}, res models.Envelope, name string, action string) error {
+	resp, err := c.sendJSON(ctx, method, requestURL, body, name, action)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return c.decodeResponse(resp, res, action)
+}
+
+func (c *Cloudflare) sendJSON(ctx context.Context, method string, requestURL string, body interface{}, name string, action string) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling request body for %s: %w", action, err)
+		}
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(withOperation(ctx, name, action), method, requestURL, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request for %s: %w", action, err)
+	}
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	c.authorize(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error %s: %w", action, err)
+	}
+	return resp, nil
+}
+
+// APIError is returned for unsuccessful API responses. Name is the client method
+// that made the request, such as "Upload", and Identifier the worker it targeted,
+// if any. Operation describes the request for logs.
+type APIError struct {
+	Name       string
+	Operation  string
+	Identifier string
+	StatusCode int
+	Status     string
+	Errors     []models.ResponseError
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	prefix := "cloudflare api error"
+	if e.Name != "" {
+		prefix = e.Name
+		if e.Identifier != "" {
+			prefix += "(" + e.Identifier + ")"
+		}
+	}
+	status := e.Status
+	if status == "" {
+		status = strconv.Itoa(e.StatusCode)
+	}
+	if len(e.Errors) > 0 {
+		return fmt.Sprintf("%s: %s: %+v", prefix, status, e.Errors)
+	}
+	return fmt.Sprintf("%s: %s: %s", prefix, status, e.Body)
+}
+
+func (e *APIError) HasCode(code int) bool {
+	for _, responseError := range e.Errors {
+		if responseError.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// newAPIError builds an APIError from an unsuccessful response, decoding the
+// response envelope if the body contains one
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}
+	errBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		apiErr.Body = err.Error()
+		return apiErr
+	}
+	envelope := new(models.Response)
+	if json.Unmarshal(errBody, envelope) == nil && len(envelope.Errors) > 0 {
+		apiErr.Errors = envelope.Errors
+	} else {
+		apiErr.Body = string(errBody)
+	}
+	return apiErr
+}
+
+// apiError records the client method and action of the request and the worker
+// identifier from the request URL in apiErr, then translates it with the
+// configured ErrorMapper
+func (c *Cloudflare) apiError(resp *http.Response, apiErr *APIError, action string) error {
+	apiErr.Operation = action
+	if resp.Request != nil {
+		apiErr.Name = operationFromContext(resp.Request.Context()).name
+		apiErr.Identifier = c.identifierFromURL(resp.Request.URL)
+	}
+	if c.options.ErrorMapper != nil {
+		if mapped := c.options.ErrorMapper(apiErr); mapped != nil {
+			return fmt.Errorf("error %s: %w", action, mapped)
+		}
+	}
+	return apiErr
+}
+
+// identifierFromURL returns the worker identifier for requests to a worker script
+// endpoint, and an empty string for any other request
+func (c *Cloudflare) identifierFromURL(u *url.URL) string {
+	scriptsPath := c.workerURL.Path + "/"
+	if !strings.HasPrefix(u.Path, scriptsPath) {
+		return ""
+	}
+	script := strings.SplitN(strings.TrimPrefix(u.Path, scriptsPath), "/", 2)[0]
+	return strings.TrimPrefix(script, c.options.Prefix)
+}
+
+func (c *Cloudflare) decodeResponse(resp *http.Response, res models.Envelope, action string) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return c.apiError(resp, newAPIError(resp), action)
+	}
+	err := json.NewDecoder(resp.Body).Decode(res)
+	if err != nil {
+		return fmt.Errorf("error decoding response for %s: %w", action, err)
+	}
+	if envelope := res.Envelope(); !envelope.Success {
+		return c.apiError(resp, &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Errors:     envelope.Errors,
+		}, action)
+	}
+	c.observeDeprecationMessages(resp, res.Envelope().Messages, action)
+	return nil
+}
+
+// authorize sets the Authorization header for req, using the ZoneToken for
+// zone-scoped operations (anything under /zones, and worker custom domains) when
+// one is configured
+func (c *Cloudflare) authorize(req *http.Request) {
+	header := c.authorizationHeader
+	if c.zoneAuthorizationHeader != "" {
+		path := strings.TrimPrefix(req.URL.String(), c.options.baseURL())
+		if strings.HasPrefix(path, "/zones/") || strings.HasPrefix(path, "/accounts/"+c.options.UserID+"/workers/domains") {
+			header = c.zoneAuthorizationHeader
+		}
+	}
+	req.Header.Set("Authorization", header)
+}
+
+func (c *Cloudflare) accountURL() string {
+	return c.options.baseURL() + "/accounts/" + c.options.UserID
+}
+
+func (c *Cloudflare) zoneURL(zoneID string) string {
+	return c.options.baseURL() + "/zones/" + zoneID
+}
+
+func (c *Cloudflare) scriptURL(identifier string) string {
+	return c.workerURL.String() + "/" + c.options.Prefix + identifier
+}
@@ -0,0 +1,104 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *APIError
+		want string
+	}{
+		{
+			name: "method and identifier",
+			err:  &APIError{Name: "Upload", Identifier: "my-worker", StatusCode: 400, Status: "400 Bad Request", Errors: []models.ResponseError{{Code: 10021, Message: "bad script"}}},
+			want: "Upload(my-worker): 400 Bad Request: [{Code:10021 Message:bad script}]",
+		},
+		{
+			name: "method only",
+			err:  &APIError{Name: "ListFunctions", StatusCode: 403, Status: "403 Forbidden", Body: "denied"},
+			want: "ListFunctions: 403 Forbidden: denied",
+		},
+		{
+			name: "no method",
+			err:  &APIError{StatusCode: 500, Body: "oops"},
+			want: "cloudflare api error: 500: oops",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		call       func(c *Cloudflare) error
+		wantName   string
+		wantID     string
+		wantAction string
+	}{
+		{
+			name:       "worker method",
+			call:       func(c *Cloudflare) error { return c.Delete(context.Background(), "my-worker") },
+			wantName:   "Delete",
+			wantID:     "my-worker",
+			wantAction: "deleting worker",
+		},
+		{
+			name: "account method",
+			call: func(c *Cloudflare) error {
+				_, err := c.ListFunctions(context.Background())
+				return err
+			},
+			wantName:   "ListFunctions",
+			wantAction: "listing workers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"bad request"}]}`))
+			}), nil)
+
+			var apiErr *APIError
+			if err := tt.call(c); !errors.As(err, &apiErr) {
+				t.Fatalf("error = %v, want an *APIError", err)
+			}
+			if apiErr.Name != tt.wantName || apiErr.Identifier != tt.wantID || apiErr.Operation != tt.wantAction {
+				t.Errorf("APIError = %+v, want name %q, identifier %q and operation %q", apiErr, tt.wantName, tt.wantID, tt.wantAction)
+			}
+			if apiErr.StatusCode != http.StatusBadRequest || !apiErr.HasCode(10000) {
+				t.Errorf("APIError = %+v, want status 400 with code 10000", apiErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultBatchConcurrency = 4
+)
+
+type BatchOptions struct {
+	// Namespace is prepended to every identifier in the batch, after the client's
+	// Prefix, so that the whole batch can later be removed with DeleteByPrefix
+	Namespace   string
+	Concurrency int
+
+	// ProgressFunc is called with the cumulative progress of every upload in the
+	// batch. Calls are serialized, so it does not need to be safe for concurrent use.
+	ProgressFunc ProgressFunc
+
+	// FailFast cancels the operations in flight and skips the rest of the batch on
+	// the first failure, instead of attempting every item. Skipped items fail with
+	// ErrBatchAborted.
+	FailFast bool
+}
+
+func (o *BatchOptions) namespace() string {
+	if o == nil {
+		return ""
+	}
+	return o.Namespace
+}
+
+func (o *BatchOptions) failFast() bool {
+	return o != nil && o.FailFast
+}
+
+func (o *BatchOptions) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return DefaultBatchConcurrency
+	}
+	return o.Concurrency
+}
+
+// BatchResult is the outcome of a single item in a batch operation. Uploaded is
+// only set for successful uploads.
+type BatchResult struct {
+	Identifier string
+	Uploaded   *bindings.UploadedFunction
+	Err        error
+}
+
+// BatchError is returned alongside the results of a batch operation when any of
+// its items failed
+type BatchError struct {
+	Failed []BatchResult
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, 0, len(e.Failed))
+	for _, result := range e.Failed {
+		messages = append(messages, fmt.Sprintf("%s: %s", result.Identifier, result.Err))
+	}
+	return fmt.Sprintf("%d batch operations failed: %s", len(e.Failed), strings.Join(messages, "; "))
+}
+
+func batchError(results []BatchResult) error {
+	var failed []BatchResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &BatchError{Failed: failed}
+}
+
+// UploadFunctions uploads every input, returning a result per input in the same
+// order and a *BatchError if any of them failed
+func (c *Cloudflare) UploadFunctions(ctx context.Context, inputs []*UploadInput, options *BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(inputs))
+	namespace := options.namespace()
+	var aggregate *progressAggregate
+	if options != nil && options.ProgressFunc != nil {
+		aggregate = &progressAggregate{fn: options.ProgressFunc}
+	}
+	for i := range results {
+		results[i].Identifier = namespace + inputs[i].Identifier
+	}
+	started := runBatch(ctx, len(inputs), options.concurrency(), options.failFast(), func(ctx context.Context, i int) error {
+		input := *inputs[i]
+		input.Identifier = results[i].Identifier
+		if aggregate != nil {
+			input.ProgressFunc = aggregate.track(input.ProgressFunc)
+		}
+		results[i].Uploaded, results[i].Err = c.Upload(ctx, &input)
+		return results[i].Err
+	})
+	abortBatch(results[started:])
+	return results, batchError(results)
+}
+
+// DeleteFunctions deletes every identifier, returning a result per identifier in
+// the same order and a *BatchError if any of them failed
+func (c *Cloudflare) DeleteFunctions(ctx context.Context, identifiers []string, options *BatchOptions) ([]BatchResult, error) {
+	results := make([]BatchResult, len(identifiers))
+	namespace := options.namespace()
+	for i := range results {
+		results[i].Identifier = namespace + identifiers[i]
+	}
+	started := runBatch(ctx, len(identifiers), options.concurrency(), options.failFast(), func(ctx context.Context, i int) error {
+		results[i].Err = c.Delete(ctx, results[i].Identifier)
+		return results[i].Err
+	})
+	abortBatch(results[started:])
+	return results, batchError(results)
+}
+
+// BulkEnable enables the workers.dev subdomain of every worker. If any of them
+// fails to enable, the ones that were enabled are disabled again so that either
+// the whole set is serving or none of it is.
+func (c *Cloudflare) BulkEnable(ctx context.Context, identifiers []string) error {
+	errs := make([]error, len(identifiers))
+	started := runBatch(ctx, len(identifiers), DefaultBatchConcurrency, false, func(ctx context.Context, i int) error {
+		errs[i] = c.EnableSubdomain(ctx, identifiers[i])
+		return errs[i]
+	})
+	for i := started; i < len(identifiers); i++ {
+		errs[i] = ErrBatchAborted
+	}
+
+	var failed []BatchResult
+	var enabled []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, BatchResult{Identifier: identifiers[i], Err: err})
+		} else {
+			enabled = append(enabled, identifiers[i])
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	runBatch(ctx, len(enabled), DefaultBatchConcurrency, false, func(ctx context.Context, i int) error {
+		err := c.DisableSubdomain(ctx, enabled[i])
+		if err != nil {
+			c.log(ctx).Error().Err(c.redactErr(err)).Str("identifier", enabled[i]).Msg("error rolling back worker subdomain")
+		}
+		return err
+	})
+	return &BatchError{Failed: failed}
+}
+
+// DeleteByPrefix deletes every worker whose identifier starts with prefix, returning
+// the identifiers that were deleted. An empty prefix is rejected with ErrEmptyPrefix
+// rather than deleting every worker.
+func (c *Cloudflare) DeleteByPrefix(ctx context.Context, prefix string, options *BatchOptions) ([]string, error) {
+	if prefix == "" {
+		return nil, ErrEmptyPrefix
+	}
+
+	scripts, err := c.ListFunctions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var identifiers []string
+	for _, script := range scripts {
+		if strings.HasPrefix(script.ID, c.options.Prefix+prefix) {
+			identifiers = append(identifiers, strings.TrimPrefix(script.ID, c.options.Prefix))
+		}
+	}
+
+	deleteOptions := &BatchOptions{
+		Concurrency: options.concurrency(),
+		FailFast:    options.failFast(),
+	}
+	results, err := c.DeleteFunctions(ctx, identifiers, deleteOptions)
+	var deleted []string
+	for _, result := range results {
+		if result.Err == nil {
+			deleted = append(deleted, result.Identifier)
+		}
+	}
+	return deleted, err
+}
+
+// runBatch calls fn for items 0 to n-1 with at most concurrency calls in flight,
+// returning how many items were started. No further items are started once ctx is
+// done. With failFast, the first error also cancels the context passed to the
+// calls in flight and stops further items.
+func runBatch(ctx context.Context, n int, concurrency int, failFast bool, fn func(ctx context.Context, i int) error) int {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	started := 0
+	for ; started < n; started++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// a slot may have been acquired after the cancellation
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(ctx, i); err != nil && failFast {
+				cancel()
+			}
+		}(started)
+	}
+	wg.Wait()
+	return started
+}
+
+func abortBatch(results []BatchResult) {
+	for i := range results {
+		results[i].Err = ErrBatchAborted
+	}
+}
@@ -0,0 +1,87 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDeleteByPrefix(t *testing.T) {
+	const scriptsPath = "/accounts/account/workers/scripts"
+	tests := []struct {
+		name         string
+		clientPrefix string
+		prefix       string
+		scripts      string
+		deleted      []string
+		err          error
+	}{
+		{
+			name:    "empty prefix",
+			prefix:  "",
+			scripts: `{"success":true,"result":[{"id":"app-one"}]}`,
+			err:     ErrEmptyPrefix,
+		},
+		{
+			name:    "unprefixed client",
+			prefix:  "app-",
+			scripts: `{"success":true,"result":[{"id":"app-one"},{"id":"app-two"},{"id":"other"}]}`,
+			deleted: []string{"app-one", "app-two"},
+		},
+		{
+			name:         "prefixed client",
+			clientPrefix: "team-",
+			prefix:       "app-",
+			scripts:      `{"success":true,"result":[{"id":"team-app-one"},{"id":"app-two"},{"id":"team-other"}]}`,
+			deleted:      []string{"app-one"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(map[string]string{
+				"GET " + scriptsPath:                      tt.scripts,
+				"DELETE " + scriptsPath + "/app-one":      `{"success":true}`,
+				"DELETE " + scriptsPath + "/app-two":      `{"success":true}`,
+				"DELETE " + scriptsPath + "/team-app-one": `{"success":true}`,
+			})
+			c, _ := newTestClient(t, api, func(options *Options) {
+				options.Prefix = tt.clientPrefix
+			})
+
+			deleted, err := c.DeleteByPrefix(context.Background(), tt.prefix, nil)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			sort.Strings(deleted)
+			if !reflect.DeepEqual(deleted, tt.deleted) {
+				t.Fatalf("expected %v to be deleted, got %v", tt.deleted, deleted)
+			}
+			for _, identifier := range tt.deleted {
+				if n := api.count("DELETE " + scriptsPath + "/" + tt.clientPrefix + identifier); n != 1 {
+					t.Fatalf("expected one delete for %s, got %d", identifier, n)
+				}
+			}
+			if tt.err != nil && len(api.requests) != 0 {
+				t.Fatalf("expected no requests, got %v", api.requests)
+			}
+		})
+	}
+}
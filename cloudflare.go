@@ -17,45 +17,221 @@
 package cloudflare
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"github.com/loopholelabs/cloudflare/pkg/bindings"
 	"github.com/loopholelabs/cloudflare/pkg/models"
 	"github.com/rs/zerolog"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"net/textproto"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 var (
-	ErrDisabled = errors.New("cloudflare is disabled")
+	ErrDisabled                    = errors.New("cloudflare is disabled")
+	ErrClosed                      = errors.New("cloudflare client is closed")
+	ErrCloseTimeout                = errors.New("timed out waiting for in-flight requests to finish")
+	ErrMissingHandler              = errors.New("worker is missing expected handler")
+	ErrUserIDRequired              = errors.New("user id is required")
+	ErrTokenRequired               = errors.New("token is required")
+	ErrInvalidBaseURL              = errors.New("invalid base url")
+	ErrVersionDeployed             = errors.New("version is currently deployed")
+	ErrUnknownCompatibilityFlag    = errors.New("unknown compatibility flag")
+	ErrSubdomainNotRegistered      = errors.New("workers subdomain is not registered for this account")
+	ErrInvalidMetadata             = errors.New("invalid metadata")
+	ErrSubdomainNotUpdated         = errors.New("worker subdomain was not updated")
+	ErrEmptyWrapper                = errors.New("generated wrapper script is empty")
+	ErrDuplicateBinding            = errors.New("duplicate binding name")
+	ErrPreBundledExtraParts        = errors.New("pre-bundled upload must contain only the entrypoint part")
+	ErrSubdomainTaken              = errors.New("workers subdomain is already taken")
+	ErrFunctionNotFound            = errors.New("worker not found")
+	ErrEtagMismatch                = errors.New("worker etag does not match")
+	ErrModulesRequireMainModule    = errors.New("modules can only be uploaded with a main module")
+	ErrNoRoutes                    = errors.New("worker has no routes or custom domains")
+	ErrInvalidBinding              = errors.New("invalid bindings")
+	ErrDuplicatePart               = errors.New("duplicate multipart part name")
+	ErrEmptySnapshot               = errors.New("snapshot has no modules")
+	ErrBatchAborted                = errors.New("batch aborted after an earlier failure")
+	ErrUsageModelNotApplied        = errors.New("usage model was not applied")
+	ErrStaleWrapper                = errors.New("wrapper script references functions that are not uploaded")
+	ErrReservedName                = errors.New("worker name is reserved")
+	ErrUnknownCompatibilityPreset  = errors.New("unknown compatibility preset")
+	ErrIncompleteClientCertificate = errors.New("client certificate and key files must be set together")
+	ErrEmptyPrefix                 = errors.New("prefix must not be empty")
+)
+
+const (
+	DefaultBaseURL              = "https://api.cloudflare.com/client/v4"
+	DefaultEntrypointName       = "worker.js"
+	DefaultPythonEntrypointName = "worker.py"
+	DefaultPerPage              = 100
+	MaxPerPage                  = 1000
 )
 
 type Options struct {
-	LogName            string
-	Disabled           bool
-	UserID             string
-	Token              string
-	Prefix             string
-	UpstreamRootDomain string
+	LogName                      string
+	Disabled                     bool
+	UserID                       string
+	Token                        string
+	ZoneToken                    string
+	BaseURL                      string
+	Prefix                       string
+	UpstreamRootDomain           string
+	DialRetries                  int
+	DialRetryInterval            time.Duration
+	DialContext                  func(ctx context.Context, network string, address string) (net.Conn, error)
+	ForceIPv4                    bool
+	LocalAddr                    net.Addr
+	StrictCompatibilityFlags     bool
+	AdditionalCompatibilityFlags []string
+	CompatibilityPresets         map[string]CompatibilityPreset
+	ErrorMapper                  func(*APIError) error
+	ObserveRateLimit             func(RateLimitStatus)
+	ObserveDeprecation           func(Deprecation)
+	SubdomainBestEffort          bool
+	RedactAccountID              bool
+	ObserveFunc                  func(Observation)
+	PerPage                      int
+	MaxRetries                   int
+	BaseBackoff                  time.Duration
+	MaxBackoff                   time.Duration
+	Sleeper                      Sleeper
+
+	// ContextKeys adds the values found in a request's context under these keys to
+	// log events and Observation.Fields, keyed by field name
+	ContextKeys map[string]interface{}
+
+	// IsReservedName rejects uploads of matching script names, see ReservedNames
+	IsReservedName func(scriptName string) bool
+
+	// StrictWrapperReferences fails uploads whose wrapper references functions
+	// that are not uploaded, instead of logging a warning
+	StrictWrapperReferences bool
+
+	// Limiter bounds the rate and concurrency of requests, and may be shared
+	// between clients
+	Limiter *Limiter
+
+	// ClientCertificates, and the pair loaded from ClientCertFile and
+	// ClientKeyFile, are presented to mutual TLS proxies in front of the API
+	ClientCertificates []tls.Certificate
+	ClientCertFile     string
+	ClientKeyFile      string
+
+	// HedgeDelay sends a second copy of GET requests not answered within it. The
+	// copy counts against Limiter like any other request
+	HedgeDelay time.Duration
+
+	// SerializeDeploys serializes uploads and deletes of the same worker within
+	// this process
+	SerializeDeploys bool
+
+	// PriorityHeader carries the Priority set with WithPriority, as its lowercase
+	// name unless PriorityValues maps it to another value
+	PriorityHeader string
+	PriorityValues map[Priority]string
+
+	// DisableCompression stops the client asking for gzip or deflate responses
+	DisableCompression bool
+
+	// SecretConcurrency bounds the concurrent requests of PutSecrets, and defaults
+	// to DefaultSecretConcurrency
+	SecretConcurrency int
+
+	// BodyTransform replaces multipart upload bodies and sets the returned headers,
+	// and is called again for every retry
+	BodyTransform func(r io.Reader) (io.Reader, http.Header, error)
+}
+
+func (o *Options) Validate() error {
+	if o.UserID == "" {
+		return ErrUserIDRequired
+	}
+
+	if o.Token == "" {
+		return ErrTokenRequired
+	}
+
+	if (o.ClientCertFile == "") != (o.ClientKeyFile == "") {
+		return ErrIncompleteClientCertificate
+	}
+
+	if o.BaseURL != "" {
+		u, err := url.Parse(o.BaseURL)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidBaseURL, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%w: %q must be an absolute url", ErrInvalidBaseURL, o.BaseURL)
+		}
+	}
+
+	return nil
+}
+
+func (o *Options) perPage() int {
+	if o.PerPage <= 0 {
+		return DefaultPerPage
+	}
+	if o.PerPage > MaxPerPage {
+		return MaxPerPage
+	}
+	return o.PerPage
+}
+
+func (o *Options) secretConcurrency() int {
+	if o.SecretConcurrency <= 0 {
+		return DefaultSecretConcurrency
+	}
+	return o.SecretConcurrency
+}
+
+func (o *Options) baseURL() string {
+	if o.BaseURL == "" {
+		return DefaultBaseURL
+	}
+	return strings.TrimSuffix(o.BaseURL, "/")
 }
 
+// Cloudflare is safe for concurrent use by multiple goroutines. Its options are
+// copied by New and never modified, and all state shared between requests is
+// guarded by a mutex or updated atomically.
 type Cloudflare struct {
 	logger  *zerolog.Logger
 	options *Options
 
-	workerURL           *url.URL
-	authorizationHeader string
+	client                  *http.Client
+	probeClient             *http.Client
+	tailClient              *http.Client
+	workerURL               *url.URL
+	authorizationHeader     string
+	zoneAuthorizationHeader string
+
+	subdomainMu sync.Mutex
+	subdomain   string
+
+	rateLimitMu sync.Mutex
+	rateLimit   *RateLimitStatus
 
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	deployLocksMu sync.Mutex
+	deployLocks   map[string]*deployLock
+
+	// closeMu orders in-flight request registration against Close, so that wg.Add
+	// never races with wg.Wait
+	closeMu sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
 }
 
 func New(options *Options, logger *zerolog.Logger) (*Cloudflare, error) {
@@ -65,22 +241,65 @@ func New(options *Options, logger *zerolog.Logger) (*Cloudflare, error) {
 		return nil, ErrDisabled
 	}
 
-	workerURL, err := url.Parse("https://api.cloudflare.com/client/v4/accounts/" + options.UserID + "/workers/scripts")
+	err := options.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	optionsCopy := *options
+	options = &optionsCopy
+
+	workerURL, err := url.Parse(options.baseURL() + "/accounts/" + options.UserID + "/workers/scripts")
 	if err != nil {
 		return nil, err
 	}
 
 	authorizationHeader := fmt.Sprintf("Bearer %s", options.Token)
+	var zoneAuthorizationHeader string
+	if options.ZoneToken != "" {
+		zoneAuthorizationHeader = fmt.Sprintf("Bearer %s", options.ZoneToken)
+	}
+
+	transport, err := newTransport(options, &l)
+	if err != nil {
+		return nil, err
+	}
+	var base http.RoundTripper = transport
+	if options.HedgeDelay > 0 {
+		base = &hedgedTransport{base: base, delay: options.HedgeDelay, limiter: options.Limiter}
+	}
+
+	probeTransport, err := newProbeTransport(options, &l)
+	if err != nil {
+		return nil, err
+	}
+
+	tailTransport, err := newTailTransport(options, &l)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	e := &Cloudflare{
-		logger:              &l,
-		options:             options,
-		workerURL:           workerURL,
-		authorizationHeader: authorizationHeader,
-		ctx:                 ctx,
-		cancel:              cancel,
+		logger:  &l,
+		options: options,
+		client: &http.Client{Transport: &AuthTransport{
+			Base:               base,
+			Token:              options.Token,
+			MaxRetries:         options.MaxRetries,
+			BaseBackoff:        options.BaseBackoff,
+			MaxBackoff:         options.MaxBackoff,
+			Sleeper:            options.Sleeper,
+			Limiter:            options.Limiter,
+			DisableCompression: options.DisableCompression,
+		}},
+		probeClient:             &http.Client{Transport: probeTransport},
+		tailClient:              &http.Client{Transport: tailTransport},
+		workerURL:               workerURL,
+		authorizationHeader:     authorizationHeader,
+		zoneAuthorizationHeader: zoneAuthorizationHeader,
+		ctx:                     ctx,
+		cancel:                  cancel,
 	}
 
 	return e, nil
@@ -88,160 +307,295 @@ func New(options *Options, logger *zerolog.Logger) (*Cloudflare, error) {
 
 func (c *Cloudflare) Close() error {
 	c.logger.Debug().Msg("closing cloudflare client")
+	c.closeMu.Lock()
 	c.cancel()
+	c.closeMu.Unlock()
 	defer c.wg.Wait()
 	return nil
 }
 
-func (c *Cloudflare) UploadFunction(identifier string, wrapperScript []byte, functions []*bindings.Function) (*bindings.UploadedFunction, error) {
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	wrapperScriptReader := bytes.NewReader(wrapperScript)
-	err := addPart(writer, "worker.js", "worker.js", "application/javascript", wrapperScriptReader)
+func (c *Cloudflare) CloseWithTimeout(ctx context.Context) error {
+	c.logger.Debug().Msg("closing cloudflare client")
+	c.closeMu.Lock()
+	c.cancel()
+	c.closeMu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", ErrCloseTimeout, ctx.Err())
+	}
+}
+
+func (c *Cloudflare) DeleteFunction(identifier string) error {
+	return c.Delete(c.ctx, identifier)
+}
+
+func (c *Cloudflare) Delete(ctx context.Context, identifier string) error {
+	unlock, err := c.lockIdentifier(ctx, identifier)
 	if err != nil {
-		return nil, fmt.Errorf("error adding wrapper script to multipart request: %w", err)
+		return err
 	}
+	defer unlock()
+	return c.doJSON(ctx, "DELETE", c.scriptURL(identifier), nil, new(models.Response), "Delete", "deleting worker")
+}
 
-	for _, function := range functions {
-		sfReader := bytes.NewReader(function.Source)
-		name := fmt.Sprintf("%s.bin", function.Identifier)
-		err = addPart(writer, name, name, "application/octet-stream", sfReader)
+// DeleteIfMatch deletes the worker only if its current etag matches etag,
+// returning ErrEtagMismatch if it was modified since the etag was observed
+func (c *Cloudflare) DeleteIfMatch(ctx context.Context, identifier string, etag string) error {
+	unlock, err := c.lockIdentifier(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	action := "deleting worker"
+	req, err := http.NewRequestWithContext(withOperation(ctx, "DeleteIfMatch", action), "DELETE", c.scriptURL(identifier), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request for %s: %w", action, err)
+	}
+	c.authorize(req)
+	if !strings.HasPrefix(etag, `"`) {
+		etag = strconv.Quote(etag)
+	}
+	req.Header.Add("If-Match", etag)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("error %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("%w: %s", ErrEtagMismatch, identifier)
+	}
+
+	return c.decodeResponse(resp, new(models.Response), action)
+}
+
+func (c *Cloudflare) ListFunctions(ctx context.Context) ([]models.Script, error) {
+	return c.listFunctions(ctx, nil)
+}
+
+// ListFunctionsByTag lists only the workers carrying all of the given tags,
+// filtered server side
+func (c *Cloudflare) ListFunctionsByTag(ctx context.Context, tags []string) ([]models.Script, error) {
+	filters := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		filters = append(filters, tag+":yes")
+	}
+	return c.listFunctions(ctx, url.Values{"tags": {strings.Join(filters, ",")}})
+}
+
+func (c *Cloudflare) listFunctions(ctx context.Context, query url.Values) ([]models.Script, error) {
+	var scripts []models.Script
+	err := c.walkFunctions(ctx, query, func(script models.Script) bool {
+		scripts = append(scripts, script)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scripts, nil
+}
+
+// WalkFunctions calls fn for every worker in the account, fetching Options.PerPage
+// workers at a time, and stops fetching pages once fn returns false
+func (c *Cloudflare) WalkFunctions(ctx context.Context, fn func(models.Script) bool) error {
+	return c.walkFunctions(ctx, nil, fn)
+}
+
+func (c *Cloudflare) walkFunctions(ctx context.Context, query url.Values, fn func(models.Script) bool) error {
+	return c.paginate(ctx, c.workerURL.String(), query, c.options.perPage(), func(ctx context.Context, page int, pageURL string) (int, *models.ResultInfo, bool, error) {
+		res := new(models.ScriptsResponse)
+		err := c.doJSON(ctx, "GET", pageURL, nil, res, "ListFunctions", "listing workers")
 		if err != nil {
-			return nil, fmt.Errorf("error adding function to multipart request: %w", err)
+			return 0, nil, false, err
+		}
+		if wrongPage(res.ResultInfo, page) {
+			return 0, res.ResultInfo, true, nil
 		}
 
-		for _, file := range function.Files {
-			reader := bytes.NewReader(file.Content)
-			name = fmt.Sprintf("%s.%s", function.Identifier, file.Extension)
-			err = addPart(writer, name, name, file.ContentType, reader)
-			if err != nil {
-				return nil, fmt.Errorf("error adding file to multipart request: %w", err)
+		for _, script := range res.Result {
+			if !fn(script) {
+				return len(res.Result), res.ResultInfo, true, nil
 			}
 		}
-	}
+		return len(res.Result), res.ResultInfo, false, nil
+	})
+}
 
-	workers := make([]bindings.Worker, 0, len(functions)*2)
-	for _, function := range functions {
-		workers = append(workers, bindings.Worker{
-			Type: "data_blob",
-			Name: fmt.Sprintf("__SF_%s", function.Identifier),
-			Part: fmt.Sprintf("%s.bin", function.Identifier),
-		})
-
-		for _, file := range function.Files {
-			workers = append(workers, bindings.Worker{
-				Type: file.Type,
-				Name: fmt.Sprintf("__%s_%s", file.Binding, function.Identifier),
-				Part: fmt.Sprintf("%s.%s", function.Identifier, file.Extension),
-			})
+// GetFunction returns the script metadata for identifier, or ErrFunctionNotFound
+// if no such worker exists
+func (c *Cloudflare) GetFunction(ctx context.Context, identifier string) (*models.Script, error) {
+	var found *models.Script
+	err := c.WalkFunctions(ctx, func(script models.Script) bool {
+		if script.ID == c.options.Prefix+identifier {
+			found = &script
+			return false
 		}
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	metadata := bindings.Metadata{
-		BodyPart: "worker.js",
-		Bindings: workers,
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFunctionNotFound, identifier)
 	}
-	metadataJSON, err := json.Marshal(metadata)
+
+	return found, nil
+}
+
+// FunctionExists reports whether a worker named identifier exists. Unlike
+// GetFunction it only returns an error when the lookup itself fails.
+func (c *Cloudflare) FunctionExists(ctx context.Context, identifier string) (bool, error) {
+	_, err := c.GetFunction(ctx, identifier)
 	if err != nil {
-		return nil, fmt.Errorf("error marshaling metadata: %w", err)
+		if errors.Is(err, ErrFunctionNotFound) {
+			return false, nil
+		}
+		return false, err
 	}
-	err = addPart(writer, "metadata", "metadata.json", "application/json", bytes.NewReader(metadataJSON))
+	return true, nil
+}
+
+// GetModifiedOn returns when the worker was last modified, so callers can detect
+// whether it was changed by someone else since their last deploy
+func (c *Cloudflare) GetModifiedOn(ctx context.Context, identifier string) (time.Time, error) {
+	script, err := c.GetFunction(ctx, identifier)
 	if err != nil {
-		return nil, fmt.Errorf("error adding metadata to multipart request: %w", err)
+		return time.Time{}, err
 	}
 
-	err = writer.Close()
+	modifiedOn, err := time.Parse(time.RFC3339Nano, script.ModifiedOn)
 	if err != nil {
-		return nil, fmt.Errorf("error closing multipart writer: %w", err)
+		return time.Time{}, fmt.Errorf("error parsing modified_on %q: %w", script.ModifiedOn, err)
+	}
+	return modifiedOn, nil
+}
+
+// CreateFunctionIfNotExists uploads input only if the worker does not already
+// exist, and never modifies an existing worker
+func (c *Cloudflare) CreateFunctionIfNotExists(ctx context.Context, identifier string, input *UploadInput) (bool, error) {
+	exists, err := c.FunctionExists(ctx, identifier)
+	if err != nil || exists {
+		return false, err
 	}
 
-	requestURL := c.workerURL.String() + "/" + c.options.Prefix + identifier + "?include_subdomain_availability=true&excludeScript=true"
-	req, err := http.NewRequest("PUT", requestURL, body)
+	upload := *input
+	upload.Identifier = identifier
+	_, err = c.Upload(ctx, &upload)
 	if err != nil {
-		return nil, fmt.Errorf("error creating upload request: %w", err)
+		return false, err
 	}
-	req.Header.Add("Content-Type", writer.FormDataContentType())
-	req.Header.Add("Authorization", c.authorizationHeader)
-	resp, err := http.DefaultClient.Do(req)
+
+	return true, nil
+}
+
+func (c *Cloudflare) UpstreamRootDomain() string {
+	return c.options.UpstreamRootDomain
+}
+
+// do sends req bound to the lifetime of the client, and tracks it as in-flight
+// until the response body is closed so that Close can wait for it to drain.
+func (c *Cloudflare) do(req *http.Request) (*http.Response, error) {
+	ctx, done, err := c.track(req)
 	if err != nil {
-		return nil, fmt.Errorf("error uploading worker: %w", err)
+		return nil, err
 	}
-	if resp.StatusCode != 200 {
-		errBody, err := io.ReadAll(resp.Body)
+	release := func() {}
+	if c.options.Limiter != nil {
+		acquired, err := c.options.Limiter.Acquire(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error uploading worker (%d: %s): %w", resp.StatusCode, resp.Status, err)
+			done()
+			if req.Body != nil {
+				_ = req.Body.Close()
+			}
+			return nil, err
 		}
-		return nil, fmt.Errorf("error uploading worker (%d: %s): %s", resp.StatusCode, resp.Status, errBody)
+		release = acquired
 	}
-	res := new(models.UploadResponse)
-	err = json.NewDecoder(resp.Body).Decode(&res)
+	c.setPriority(req)
+	ctx, stats := withRequestStats(ctx)
+	start := time.Now()
+	resp, err := c.client.Do(req.WithContext(ctx))
+	c.observe(req, resp, err, start, stats)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding upload response: %w", err)
-	}
-	if !res.Success {
-		return nil, fmt.Errorf("error uploading worker: %+v", res.Errors)
+		release()
+		done()
+		return nil, err
 	}
+	c.observeRateLimit(resp)
+	c.observeDeprecationHeaders(req, resp)
+	resp.Body = &trackedBody{ReadCloser: resp.Body, done: func() {
+		release()
+		done()
+	}}
+	return resp, nil
+}
 
-	if !res.Result.AvailableOnSubdomain {
-		requestURL = c.workerURL.String() + "/" + c.options.Prefix + identifier + "/subdomain"
-		req, err = http.NewRequest("POST", requestURL, bytes.NewBufferString("{\"enabled\": true}"))
-		if err != nil {
-			return nil, fmt.Errorf("error creating subdomain request: %w", err)
-		}
-		req.Header.Add("Content-Type", "application/json")
-		req.Header.Add("Authorization", c.authorizationHeader)
-		resp, err = http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("error creating subdomain: %w", err)
-		}
-		if resp.StatusCode != 200 {
-			errBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return nil, fmt.Errorf("error creating subdomain (%d: %s): %w", resp.StatusCode, resp.Status, err)
-			}
-			return nil, fmt.Errorf("error creating subdomain (%d: %s): %s", resp.StatusCode, resp.Status, errBody)
+// track registers req as in-flight, returning a context that is also canceled
+// when the client is closed and a func that must be called once it is finished
+func (c *Cloudflare) track(req *http.Request) (context.Context, func(), error) {
+	c.closeMu.RLock()
+	if c.ctx.Err() != nil {
+		c.closeMu.RUnlock()
+		if req.Body != nil {
+			_ = req.Body.Close()
 		}
+		return nil, nil, ErrClosed
 	}
-
-	return &bindings.UploadedFunction{
-		Identifier: identifier,
-		Subdomain:  c.options.Prefix + identifier,
+	c.wg.Add(1)
+	c.closeMu.RUnlock()
+	ctx, cancel := context.WithCancel(req.Context())
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		c.wg.Done()
 	}, nil
 }
 
-func (c *Cloudflare) DeleteFunction(identifier string) error {
-	requestURL := c.workerURL.String() + "/" + c.options.Prefix + identifier
-	req, err := http.NewRequest("DELETE", requestURL, nil)
-	if err != nil {
-		return fmt.Errorf("error creating delete request: %w", err)
-	}
-	req.Header.Add("Authorization", c.authorizationHeader)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("error deleting worker: %w", err)
-	}
-	if resp.StatusCode != 200 {
-		errBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("error deleting worker (%d: %s): %w", resp.StatusCode, resp.Status, err)
-		}
-		return fmt.Errorf("error deleting worker (%d: %s): %s", resp.StatusCode, resp.Status, errBody)
-	}
-	return nil
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
 }
 
-func (c *Cloudflare) UpstreamRootDomain() string {
-	return c.options.UpstreamRootDomain
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
 }
 
-func addPart(w *multipart.Writer, name string, filename string, contentType string, r io.Reader) error {
+func addPart(w *multipart.Writer, p bindings.Part) error {
+	if _, _, err := mime.ParseMediaType(p.ContentType); err != nil {
+		return fmt.Errorf("invalid content type %q: %w", p.ContentType, err)
+	}
 	h := make(textproto.MIMEHeader)
-	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, name, filename))
-	h.Set("Content-Type", contentType)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, p.FormFieldName(), p.FormFileName()))
+	h.Set("Content-Type", p.ContentType)
 	part, err := w.CreatePart(h)
 	if err != nil {
 		return err
 	}
-	_, err = io.Copy(part, r)
+	_, err = part.Write(p.Content)
 	return err
 }
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// newTestClient returns a client whose API requests are served by handler. The
+// server is closed when the test finishes.
+func newTestClient(t *testing.T, handler http.Handler, configure func(*Options)) (*Cloudflare, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	options := &Options{
+		LogName:     "test",
+		UserID:      "account",
+		Token:       "token",
+		BaseURL:     server.URL,
+		BaseBackoff: 1,
+		MaxBackoff:  1,
+	}
+	if configure != nil {
+		configure(options)
+	}
+	logger := zerolog.Nop()
+	c, err := New(options, &logger)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, server
+}
+
+// fakeAPI serves canned responses keyed by "METHOD /path" and records every
+// request it receives. Unknown requests fail with 404.
+type fakeAPI struct {
+	mu        sync.Mutex
+	responses map[string]string
+	requests  []string
+}
+
+func newFakeAPI(responses map[string]string) *fakeAPI {
+	return &fakeAPI{responses: responses}
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	f.mu.Lock()
+	f.requests = append(f.requests, key)
+	response, ok := f.responses[key]
+	f.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10007,"message":"not found"}]}`))
+		return
+	}
+	_, _ = w.Write([]byte(response))
+}
+
+// count returns how many requests matched key
+func (f *fakeAPI) count(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, request := range f.requests {
+		if request == key {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr error
+	}{
+		{name: "valid", options: Options{UserID: "account", Token: "token"}},
+		{name: "valid base url", options: Options{UserID: "account", Token: "token", BaseURL: "https://example.com/client/v4"}},
+		{name: "missing account", options: Options{Token: "token"}, wantErr: ErrUserIDRequired},
+		{name: "missing token", options: Options{UserID: "account"}, wantErr: ErrTokenRequired},
+		{name: "relative base url", options: Options{UserID: "account", Token: "token", BaseURL: "/client/v4"}, wantErr: ErrInvalidBaseURL},
+		{name: "unparseable base url", options: Options{UserID: "account", Token: "token", BaseURL: "https://exa mple.com/%zz"}, wantErr: ErrInvalidBaseURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	logger := zerolog.Nop()
+	tests := []struct {
+		name    string
+		options Options
+		wantErr error
+	}{
+		{name: "disabled", options: Options{Disabled: true}, wantErr: ErrDisabled},
+		{name: "missing account", options: Options{Token: "token"}, wantErr: ErrUserIDRequired},
+		{name: "missing token", options: Options{UserID: "account"}, wantErr: ErrTokenRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(&tt.options, &logger)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+			if c != nil {
+				t.Errorf("New() returned a client for invalid options")
+			}
+		})
+	}
+}
+
+// TestConcurrentUse shares one client between many goroutines uploading, deleting
+// and reading cached state. It is meant to be run with -race.
+func TestConcurrentUse(t *testing.T) {
+	var requests int64
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set("Ratelimit", `"default";r=1199;t=5`)
+		w.Header().Set("Ratelimit-Policy", `"default";q=1200;w=300`)
+		switch {
+		case r.Method == "PUT":
+			_, _ = io.Copy(io.Discard, r.Body)
+			_, _ = w.Write([]byte(`{"success":true,"result":{"available_on_subdomain":false}}`))
+		case r.Method == "POST" && strings.HasSuffix(r.URL.Path, "/subdomain"):
+			_, _ = w.Write([]byte(`{"success":true,"result":{"enabled":true}}`))
+		case r.Method == "GET" && r.URL.Path == "/accounts/account/workers/subdomain":
+			_, _ = w.Write([]byte(`{"success":true,"result":{"subdomain":"example"}}`))
+		case r.Method == "DELETE":
+			_, _ = w.Write([]byte(`{"success":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}), func(options *Options) {
+		options.SerializeDeploys = true
+		options.Limiter = NewLimiter(0, 0, 8)
+		options.ObserveFunc = func(Observation) {}
+		options.ObserveRateLimit = func(RateLimitStatus) {}
+	})
+
+	const workers = 8
+	const rounds = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds*4)
+	for i := 0; i < workers; i++ {
+		identifier := fmt.Sprintf("worker-%d", i%3)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				uploaded, err := c.Upload(context.Background(), &UploadInput{
+					Identifier:    identifier,
+					WrapperScript: []byte("export default {}"),
+					MainModule:    true,
+					Functions:     []*bindings.Function{{Identifier: "fn", Source: []byte("source"), Vars: map[string]string{"A": "a", "B": "b"}}},
+				})
+				if err != nil {
+					errs <- err
+				} else if !uploaded.SubdomainEnabled {
+					errs <- fmt.Errorf("subdomain of %s was not enabled", identifier)
+				}
+				if _, err := c.GetWorkersSubdomain(context.Background()); err != nil {
+					errs <- err
+				}
+				if err := c.Delete(context.Background(), identifier); err != nil {
+					errs <- err
+				}
+				c.RateLimit()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(context.Background(), "worker-0"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Delete() after Close error = %v, want %v", err, ErrClosed)
+	}
+	if atomic.LoadInt64(&requests) < workers*rounds*3 {
+		t.Errorf("only %d requests were sent", requests)
+	}
+}
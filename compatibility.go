@@ -0,0 +1,170 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+)
+
+// pythonWorkersFlag is required by Python workers, see UploadInput.Python
+const pythonWorkersFlag = "python_workers"
+
+// knownCompatibilityFlags is not exhaustive, new flags are released regularly so
+// unknown flags are only rejected when Options.StrictCompatibilityFlags is set
+var knownCompatibilityFlags = map[string]struct{}{
+	"allow_custom_ports":                           {},
+	"ignore_custom_ports":                          {},
+	"brotli_content_encoding":                      {},
+	"no_brotli_content_encoding":                   {},
+	"capture_async_api_throws":                     {},
+	"do_not_capture_async_api_throws":              {},
+	"durable_object_fetch_requires_full_url":       {},
+	"durable_object_fetch_allows_relative_url":     {},
+	"export_commonjs_default":                      {},
+	"export_commonjs_namespace":                    {},
+	"fetch_refuses_unknown_protocols":              {},
+	"fetch_treats_unknown_protocols_as_http":       {},
+	"formdata_parser_supports_files":               {},
+	"formdata_parser_converts_files_to_strings":    {},
+	"global_navigator":                             {},
+	"no_global_navigator":                          {},
+	"html_rewriter_treats_esi_include_as_void_tag": {},
+	"minimal_subrequests":                          {},
+	"no_minimal_subrequests":                       {},
+	"nodejs_als":                                   {},
+	"nodejs_compat":                                {},
+	"nodejs_compat_v2":                             {},
+	"no_nodejs_compat_v2":                          {},
+	"python_workers":                               {},
+	"r2_list_honor_include":                        {},
+	"no_r2_list_honor_include":                     {},
+	"response_json":                                {},
+	"no_response_json":                             {},
+	"streams_enable_constructors":                  {},
+	"streams_disable_constructors":                 {},
+	"transformstream_enable_standard_constructor":  {},
+	"transformstream_disable_standard_constructor": {},
+	"url_standard":                                 {},
+	"url_original":                                 {},
+	"web_socket_compression":                       {},
+	"no_web_socket_compression":                    {},
+}
+
+func IsKnownCompatibilityFlag(flag string) bool {
+	_, ok := knownCompatibilityFlags[flag]
+	return ok
+}
+
+// checkCompatibilityFlags rejects or warns about unknown flags. Flags listed in
+// Options.AdditionalCompatibilityFlags are treated as known, and allowUnknown skips
+// the check entirely for flags newer than this package.
+func (c *Cloudflare) checkCompatibilityFlags(ctx context.Context, identifier string, flags []string, allowUnknown bool) error {
+	if allowUnknown {
+		return nil
+	}
+	for _, flag := range flags {
+		if IsKnownCompatibilityFlag(flag) || containsString(c.options.AdditionalCompatibilityFlags, flag) {
+			continue
+		}
+		suggestion := suggestCompatibilityFlag(flag)
+		if c.options.StrictCompatibilityFlags {
+			if suggestion != "" {
+				return fmt.Errorf("%w: %s (did you mean %s?)", ErrUnknownCompatibilityFlag, flag, suggestion)
+			}
+			return fmt.Errorf("%w: %s", ErrUnknownCompatibilityFlag, flag)
+		}
+		c.log(ctx).Warn().Str("identifier", identifier).Str("flag", flag).Str("suggestion", suggestion).Msg("unknown compatibility flag")
+	}
+	return nil
+}
+
+// CompatibilityPreset is a named compatibility date and set of flags, registered in
+// Options.CompatibilityPresets and applied with UploadInput.CompatibilityPreset
+type CompatibilityPreset struct {
+	Date  string
+	Flags []string
+}
+
+// CompatibilityPreset returns the preset registered under name
+func (c *Cloudflare) CompatibilityPreset(name string) (CompatibilityPreset, bool) {
+	preset, ok := c.options.CompatibilityPresets[name]
+	return preset, ok
+}
+
+// applyCompatibilityPreset returns a copy of input with its compatibility preset
+// expanded. The preset's date is used unless input sets one, and its flags are
+// added to the input's flags.
+func (c *Cloudflare) applyCompatibilityPreset(input *UploadInput) (*UploadInput, error) {
+	if input.CompatibilityPreset == "" {
+		return input, nil
+	}
+	preset, ok := c.CompatibilityPreset(input.CompatibilityPreset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompatibilityPreset, input.CompatibilityPreset)
+	}
+
+	expanded := *input
+	if expanded.CompatibilityDate == "" {
+		expanded.CompatibilityDate = preset.Date
+	}
+	expanded.CompatibilityFlags = append([]string(nil), preset.Flags...)
+	for _, flag := range input.CompatibilityFlags {
+		if !containsString(expanded.CompatibilityFlags, flag) {
+			expanded.CompatibilityFlags = append(expanded.CompatibilityFlags, flag)
+		}
+	}
+	return &expanded, nil
+}
+
+// suggestCompatibilityFlag returns the known flag closest to flag if it is within
+// a couple of edits, to point out likely typos
+func suggestCompatibilityFlag(flag string) string {
+	best, bestDistance := "", 3
+	for known := range knownCompatibilityFlags {
+		if d := editDistance(flag, known); d < bestDistance || (d == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,46 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+)
+
+// Response compression is not configurable per worker. Cloudflare compresses
+// responses from workers according to the zone's settings, so these methods read
+// and write the zone-level brotli setting for the zone the worker is routed on.
+// Workers served only from workers.dev use Cloudflare's default compression.
+
+func (c *Cloudflare) GetZoneCompression(ctx context.Context, zoneID string) (bool, error) {
+	res := new(models.ZoneSettingResponse)
+	err := c.doJSON(ctx, "GET", c.zoneURL(zoneID)+"/settings/brotli", nil, res, "GetZoneCompression", "getting zone compression")
+	if err != nil {
+		return false, err
+	}
+
+	return res.Result.Value == "on", nil
+}
+
+func (c *Cloudflare) SetZoneCompression(ctx context.Context, zoneID string, enabled bool) error {
+	value := "off"
+	if enabled {
+		value = "on"
+	}
+	res := new(models.ZoneSettingResponse)
+	return c.doJSON(ctx, "PATCH", c.zoneURL(zoneID)+"/settings/brotli", &models.ZoneSetting{Value: value}, res, "SetZoneCompression", "setting zone compression")
+}
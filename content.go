@@ -0,0 +1,127 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+type ModuleFunc func(name string, contentType string, r io.Reader) error
+
+func (c *Cloudflare) DownloadFunction(ctx context.Context, identifier string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := c.DownloadFunctionTo(ctx, identifier, buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DownloadFunctionTo streams the raw script content to w without buffering it. For
+// workers with multiple modules this is the multipart encoded bundle, use
+// DownloadModules to receive each module individually.
+func (c *Cloudflare) DownloadFunctionTo(ctx context.Context, identifier string, w io.Writer) error {
+	resp, err := c.getContent(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("error downloading worker: %w", err)
+	}
+	return nil
+}
+
+func (c *Cloudflare) DownloadModules(ctx context.Context, identifier string, fn ModuleFunc) error {
+	_, err := c.downloadModules(ctx, identifier, fn)
+	return err
+}
+
+// downloadModules calls fn for every module of the worker in the order they were
+// returned, and returns the name of the main module from the CF-Entrypoint header
+func (c *Cloudflare) downloadModules(ctx context.Context, identifier string, fn ModuleFunc) (string, error) {
+	resp, err := c.getContent(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	mainModule := resp.Header.Get("CF-Entrypoint")
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		if mainModule == "" {
+			mainModule = DefaultEntrypointName
+		}
+		return mainModule, fn(mainModule, resp.Header.Get("Content-Type"), resp.Body)
+	}
+
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return mainModule, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading worker modules: %w", err)
+		}
+		name := part.FormName()
+		if name == "" {
+			name = part.FileName()
+		}
+		err = fn(name, part.Header.Get("Content-Type"), part)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+func (c *Cloudflare) GetModules(ctx context.Context, identifier string) ([]string, error) {
+	var modules []string
+	err := c.DownloadModules(ctx, identifier, func(name string, _ string, _ io.Reader) error {
+		modules = append(modules, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+func (c *Cloudflare) getContent(ctx context.Context, identifier string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(withOperation(ctx, "DownloadFunction", "getting worker content"), "GET", c.scriptURL(identifier)+"/content/v2", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating content request: %w", err)
+	}
+	c.authorize(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error getting worker content: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		return nil, c.apiError(resp, newAPIError(resp), "getting worker content")
+	}
+	return resp, nil
+}
@@ -0,0 +1,232 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"time"
+)
+
+type DeployInput struct {
+	Upload *UploadInput
+
+	// Routes are created and CustomDomains attached for the worker in ZoneID after
+	// it is uploaded. Domains that are already attached to the worker are kept.
+	ZoneID        string
+	Routes        []string
+	CustomDomains []string
+
+	// Schedules replaces the worker's cron triggers, unless it is nil
+	Schedules []string
+}
+
+type DeployResult struct {
+	Uploaded *bindings.UploadedFunction
+	Routes   []models.Route
+	Domains  []models.Domain
+	Rollback *RollbackToken
+}
+
+// RollbackToken records the state a Deploy replaced, so that Revert can undo it
+// later without querying the previous state again. It is safe to marshal as JSON.
+type RollbackToken struct {
+	Identifier string `json:"identifier"`
+
+	// Existed is false if the worker was created by the deploy, in which case
+	// reverting deletes it
+	Existed          bool                       `json:"existed"`
+	PreviousEtag     string                     `json:"previous_etag,omitempty"`
+	PreviousVersions []models.DeploymentVersion `json:"previous_versions,omitempty"`
+
+	ZoneID          string   `json:"zone_id,omitempty"`
+	CreatedRoutes   []string `json:"created_routes,omitempty"`
+	AttachedDomains []string `json:"attached_domains,omitempty"`
+
+	SchedulesChanged  bool     `json:"schedules_changed,omitempty"`
+	PreviousSchedules []string `json:"previous_schedules,omitempty"`
+
+	SubdomainChanged         bool `json:"subdomain_changed,omitempty"`
+	PreviousSubdomainEnabled bool `json:"previous_subdomain_enabled,omitempty"`
+}
+
+// Deploy uploads the worker, creates its routes, attaches its custom domains and
+// sets its schedules, returning
+// a RollbackToken that reverts all of them. If any step fails, the steps already
+// applied are reverted before the error is returned.
+func (c *Cloudflare) Deploy(ctx context.Context, input *DeployInput) (*DeployResult, error) {
+	identifier := input.Upload.Identifier
+	token := &RollbackToken{
+		Identifier: identifier,
+		ZoneID:     input.ZoneID,
+	}
+
+	script, err := c.GetFunction(ctx, identifier)
+	if err != nil && !errors.Is(err, ErrFunctionNotFound) {
+		return nil, err
+	}
+
+	var previousSchedules []string
+	if script != nil {
+		token.Existed = true
+		token.PreviousEtag = script.Etag
+
+		deployments, err := c.GetDeployments(ctx, identifier, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(deployments) > 0 {
+			token.PreviousVersions = deployments[0].Versions
+		}
+
+		token.PreviousSubdomainEnabled, err = c.GetSubdomain(ctx, identifier)
+		if err != nil {
+			return nil, err
+		}
+
+		if input.Schedules != nil {
+			schedules, err := c.GetCronTriggers(ctx, identifier)
+			if err != nil {
+				return nil, err
+			}
+			previousSchedules = make([]string, 0, len(schedules))
+			for _, schedule := range schedules {
+				previousSchedules = append(previousSchedules, schedule.Cron)
+			}
+		}
+	}
+
+	attached := make(map[string]struct{})
+	if len(input.CustomDomains) > 0 {
+		domains, err := c.ListDomains(ctx, identifier)
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range domains {
+			attached[domain.Hostname] = struct{}{}
+		}
+	}
+
+	uploaded, err := c.Upload(ctx, input.Upload)
+	if err != nil {
+		return nil, err
+	}
+	// the token is only completed as each step succeeds, so a failed deploy reverts
+	// exactly what it changed
+	result := &DeployResult{
+		Uploaded: uploaded,
+		Rollback: token,
+	}
+	if token.Existed && uploaded.SubdomainEnabled != token.PreviousSubdomainEnabled && managesSubdomain(input.Upload) {
+		token.SubdomainChanged = true
+	}
+
+	for _, pattern := range input.Routes {
+		route, err := c.CreateRoute(ctx, input.ZoneID, pattern, identifier)
+		if err != nil {
+			c.revertFailedDeploy(token)
+			return nil, err
+		}
+		token.CreatedRoutes = append(token.CreatedRoutes, route.ID)
+		result.Routes = append(result.Routes, *route)
+	}
+
+	for _, hostname := range input.CustomDomains {
+		if _, ok := attached[hostname]; ok {
+			continue
+		}
+		domain, err := c.AttachDomain(ctx, input.ZoneID, hostname, identifier)
+		if err != nil {
+			c.revertFailedDeploy(token)
+			return nil, err
+		}
+		token.AttachedDomains = append(token.AttachedDomains, domain.ID)
+		result.Domains = append(result.Domains, *domain)
+	}
+
+	if input.Schedules != nil {
+		err = c.PutCronTriggers(ctx, identifier, input.Schedules)
+		if err != nil {
+			c.revertFailedDeploy(token)
+			return nil, err
+		}
+		token.SchedulesChanged = true
+		token.PreviousSchedules = previousSchedules
+	}
+
+	return result, nil
+}
+
+// Revert undoes the changes recorded in token. Every step is attempted, and the
+// first error encountered is returned.
+func (c *Cloudflare) Revert(ctx context.Context, token *RollbackToken) error {
+	var firstErr error
+	record := func(err error) {
+		if err == nil {
+			return
+		}
+		if firstErr == nil {
+			firstErr = err
+			return
+		}
+		c.log(ctx).Error().Err(c.redactErr(err)).Str("identifier", token.Identifier).Msg("error reverting deploy")
+	}
+
+	for i := len(token.AttachedDomains) - 1; i >= 0; i-- {
+		record(c.DetachDomain(ctx, token.AttachedDomains[i]))
+	}
+	for i := len(token.CreatedRoutes) - 1; i >= 0; i-- {
+		record(c.DeleteRoute(ctx, token.ZoneID, token.CreatedRoutes[i]))
+	}
+
+	if !token.Existed {
+		record(c.Delete(ctx, token.Identifier))
+		return firstErr
+	}
+
+	if token.SchedulesChanged {
+		crons := token.PreviousSchedules
+		if crons == nil {
+			crons = []string{}
+		}
+		record(c.PutCronTriggers(ctx, token.Identifier, crons))
+	}
+
+	if token.SubdomainChanged {
+		record(c.SetSubdomain(ctx, token.Identifier, token.PreviousSubdomainEnabled))
+	}
+
+	if len(token.PreviousVersions) > 0 {
+		deployment := &models.DeploymentRequest{
+			Strategy: "percentage",
+			Versions: token.PreviousVersions,
+		}
+		record(c.doJSON(ctx, "POST", c.scriptURL(token.Identifier)+"/deployments", deployment, new(models.DeploymentResponse), "Revert", "reverting worker deployment"))
+	}
+
+	return firstErr
+}
+
+func (c *Cloudflare) revertFailedDeploy(token *RollbackToken) {
+	revertCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	if err := c.Revert(revertCtx, token); err != nil {
+		c.logger.Error().Err(c.redactErr(err)).Str("identifier", token.Identifier).Msg("error reverting failed deploy")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeployCustomDomains(t *testing.T) {
+	const scripts = "GET /accounts/account/workers/scripts"
+	const domains = "GET /accounts/account/workers/domains"
+	const attach = "PUT /accounts/account/workers/domains"
+	tests := []struct {
+		name         string
+		domains      string
+		attachFails  bool
+		wantAttached int
+		wantDetached map[string]int
+		wantErr      bool
+	}{
+		{
+			name:         "new domains",
+			domains:      `{"success":true,"result":[]}`,
+			wantAttached: 2,
+		},
+		{
+			name:         "already attached domain is kept",
+			domains:      `{"success":true,"result":[{"id":"existing","hostname":"a.example.com"}]}`,
+			wantAttached: 1,
+		},
+		{
+			name:        "failed attach reverts",
+			domains:     `{"success":true,"result":[]}`,
+			attachFails: true,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]string{
+				scripts: `{"success":true,"result":[],"result_info":{"page":1,"total_pages":1}}`,
+				domains: tt.domains,
+				"PUT /accounts/account/workers/scripts/worker":    `{"success":true,"result":{"id":"worker"}}`,
+				"DELETE /accounts/account/workers/scripts/worker": `{"success":true}`,
+			}
+			if !tt.attachFails {
+				responses[attach] = `{"success":true,"result":{"id":"domain-id","hostname":"a.example.com"}}`
+			}
+			api := newFakeAPI(responses)
+			c, _ := newTestClient(t, api, nil)
+
+			result, err := c.Deploy(context.Background(), &DeployInput{
+				Upload:        &UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), MainModule: true, SkipSubdomain: true},
+				ZoneID:        "zone",
+				CustomDomains: []string{"a.example.com", "b.example.com"},
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deploy() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if api.count("DELETE /accounts/account/workers/scripts/worker") != 1 {
+					t.Errorf("created worker was not deleted after the failed deploy")
+				}
+				return
+			}
+			if api.count(attach) != tt.wantAttached {
+				t.Errorf("attach requests = %d, want %d", api.count(attach), tt.wantAttached)
+			}
+			if len(result.Rollback.AttachedDomains) != tt.wantAttached || len(result.Domains) != tt.wantAttached {
+				t.Errorf("rollback domains = %v and result domains = %v, want %d", result.Rollback.AttachedDomains, result.Domains, tt.wantAttached)
+			}
+		})
+	}
+}
+
+func TestRevertDetachesDomains(t *testing.T) {
+	api := newFakeAPI(map[string]string{
+		"DELETE /accounts/account/workers/domains/d1":     `{"success":true}`,
+		"DELETE /accounts/account/workers/domains/d2":     `{"success":true}`,
+		"DELETE /zones/zone/workers/routes/r1":            `{"success":true,"result":{"id":"r1"}}`,
+		"DELETE /accounts/account/workers/scripts/worker": `{"success":true}`,
+	})
+	c, _ := newTestClient(t, api, nil)
+
+	err := c.Revert(context.Background(), &RollbackToken{
+		Identifier:      "worker",
+		ZoneID:          "zone",
+		CreatedRoutes:   []string{"r1"},
+		AttachedDomains: []string{"d1", "d2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"DELETE /accounts/account/workers/domains/d2",
+		"DELETE /accounts/account/workers/domains/d1",
+		"DELETE /zones/zone/workers/routes/r1",
+		"DELETE /accounts/account/workers/scripts/worker",
+	}
+	if len(api.requests) != len(want) {
+		t.Fatalf("requests = %v, want %v", api.requests, want)
+	}
+	for i := range want {
+		if api.requests[i] != want[i] {
+			t.Errorf("request %d = %s, want %s", i, api.requests[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/http"
+	"strings"
+)
+
+// Deprecation is a signal from the Cloudflare API that an endpoint or parameter
+// used by Operation is deprecated. It comes either from the Deprecation and Sunset
+// response headers, or from response messages mentioning a deprecation or sunset.
+type Deprecation struct {
+	Operation   string
+	Deprecation string
+	Sunset      string
+	Link        string
+	Messages    []models.ResponseError
+}
+
+func (c *Cloudflare) observeDeprecationHeaders(req *http.Request, resp *http.Response) {
+	deprecation := resp.Header.Get("Deprecation")
+	sunset := resp.Header.Get("Sunset")
+	if deprecation == "" && sunset == "" {
+		return
+	}
+
+	c.observeDeprecation(req, Deprecation{
+		Operation:   operationFromContext(req.Context()).action,
+		Deprecation: deprecation,
+		Sunset:      sunset,
+		Link:        resp.Header.Get("Link"),
+	})
+}
+
+// observeDeprecationMessages reports the messages of a successful response that
+// mention a deprecation. Cloudflare has no dedicated message code for these, so
+// the message text is matched.
+func (c *Cloudflare) observeDeprecationMessages(resp *http.Response, messages []models.ResponseError, action string) {
+	var deprecated []models.ResponseError
+	for _, message := range messages {
+		text := strings.ToLower(message.Message)
+		if strings.Contains(text, "deprecat") || strings.Contains(text, "sunset") {
+			deprecated = append(deprecated, message)
+		}
+	}
+	if len(deprecated) == 0 {
+		return
+	}
+
+	c.observeDeprecation(resp.Request, Deprecation{
+		Operation: action,
+		Messages:  deprecated,
+	})
+}
+
+func (c *Cloudflare) observeDeprecation(req *http.Request, deprecation Deprecation) {
+	event := c.logger.Warn()
+	if req != nil {
+		event = c.log(req.Context()).Warn()
+	}
+	event.Str("operation", deprecation.Operation).Str("deprecation", deprecation.Deprecation).Str("sunset", deprecation.Sunset).Str("link", deprecation.Link).Interface("messages", deprecation.Messages).Msg("cloudflare api deprecation")
+
+	if c.options.ObserveDeprecation != nil {
+		c.options.ObserveDeprecation(deprecation)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FunctionDescription is the deployed state of a worker, as returned by Describe.
+// Fields whose lookup failed are left empty and reported in *DescribeError.
+type FunctionDescription struct {
+	Identifier       string
+	Script           *models.Script
+	Settings         *models.ScriptSettings
+	Bindings         []bindings.Worker
+	Schedules        []ScheduleInfo
+	SubdomainEnabled bool
+	Domains          []models.Domain
+	Routes           []models.Route
+}
+
+// DescribeError is returned alongside a partial FunctionDescription, keyed by the
+// part of the description that could not be fetched
+type DescribeError struct {
+	Failed map[string]error
+}
+
+func (e *DescribeError) Error() string {
+	parts := make([]string, 0, len(e.Failed))
+	for part := range e.Failed {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+
+	messages := make([]string, 0, len(parts))
+	for _, part := range parts {
+		messages = append(messages, fmt.Sprintf("%s: %s", part, e.Failed[part]))
+	}
+	return fmt.Sprintf("error describing worker: %s", strings.Join(messages, "; "))
+}
+
+// Describe fetches the worker's script, settings, bindings, schedules, subdomain
+// status, custom domains and its routes in zoneIDs concurrently. Routes belong to
+// zones rather than to the worker, so only the zones given are searched. If only
+// some of the lookups fail, the rest of the description is returned with a
+// *DescribeError. A worker that does not exist returns ErrFunctionNotFound.
+func (c *Cloudflare) Describe(ctx context.Context, identifier string, zoneIDs ...string) (*FunctionDescription, error) {
+	description := &FunctionDescription{
+		Identifier: identifier,
+	}
+
+	var mu sync.Mutex
+	failed := make(map[string]error)
+	var wg sync.WaitGroup
+	fetch := func(part string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				mu.Lock()
+				failed[part] = err
+				mu.Unlock()
+			}
+		}()
+	}
+
+	fetch("script", func() (err error) {
+		description.Script, err = c.GetFunction(ctx, identifier)
+		return err
+	})
+	fetch("settings", func() (err error) {
+		description.Settings, err = c.GetSettings(ctx, identifier)
+		if err == nil {
+			description.Bindings = description.Settings.Bindings
+		}
+		return err
+	})
+	fetch("schedules", func() (err error) {
+		description.Schedules, err = c.GetSchedules(ctx, identifier)
+		return err
+	})
+	fetch("subdomain", func() (err error) {
+		description.SubdomainEnabled, err = c.GetSubdomain(ctx, identifier)
+		return err
+	})
+	fetch("domains", func() (err error) {
+		description.Domains, err = c.ListDomains(ctx, identifier)
+		return err
+	})
+	for _, zoneID := range zoneIDs {
+		zoneID := zoneID
+		fetch("routes "+zoneID, func() error {
+			routes, err := c.ListRoutes(ctx, zoneID)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, route := range routes {
+				if route.Script == c.options.Prefix+identifier {
+					description.Routes = append(description.Routes, route)
+				}
+			}
+			return nil
+		})
+	}
+	wg.Wait()
+
+	sort.Slice(description.Routes, func(i, j int) bool {
+		return description.Routes[i].Pattern < description.Routes[j].Pattern
+	})
+
+	if err := failed["script"]; errors.Is(err, ErrFunctionNotFound) {
+		return nil, err
+	}
+	if len(failed) > 0 {
+		return description, &DescribeError{Failed: failed}
+	}
+	return description, nil
+}
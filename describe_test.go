@@ -0,0 +1,131 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		zoneIDs     []string
+		failing     string
+		scripts     string
+		wantErr     error
+		wantFailed  string
+		wantRoutes  []string
+		wantDomains int
+	}{
+		{
+			name:        "without zones",
+			wantDomains: 1,
+		},
+		{
+			name:        "with zones",
+			zoneIDs:     []string{"z1", "z2"},
+			wantRoutes:  []string{"a.example.com/*", "b.example.com/*"},
+			wantDomains: 1,
+		},
+		{
+			name:        "failed zone",
+			zoneIDs:     []string{"z1", "broken"},
+			wantRoutes:  []string{"b.example.com/*"},
+			wantDomains: 1,
+			wantErr:     new(DescribeError),
+			wantFailed:  "routes broken",
+		},
+		{
+			name:        "failed settings",
+			failing:     "/accounts/account/workers/scripts/worker/settings",
+			wantErr:     new(DescribeError),
+			wantFailed:  "settings",
+			wantDomains: 1,
+		},
+		{
+			name:    "not found",
+			scripts: `{"success":true,"result":[],"result_info":{"page":1,"total_pages":1}}`,
+			wantErr: ErrFunctionNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripts := tt.scripts
+			if scripts == "" {
+				scripts = `{"success":true,"result":[{"id":"worker"}],"result_info":{"page":1,"total_pages":1}}`
+			}
+			responses := map[string]string{
+				"/accounts/account/workers/scripts":                  scripts,
+				"/accounts/account/workers/scripts/worker/settings":  `{"success":true,"result":{"bindings":[{"type":"plain_text","name":"A","text":"a"}]}}`,
+				"/accounts/account/workers/scripts/worker/schedules": `{"success":true,"result":{"schedules":[{"cron":"*/5 * * * *"}]}}`,
+				"/accounts/account/workers/scripts/worker/subdomain": `{"success":true,"result":{"enabled":true}}`,
+				"/accounts/account/workers/domains":                  `{"success":true,"result":[{"hostname":"app.example.com","service":"worker"}]}`,
+				"/zones/z1/workers/routes":                           `{"success":true,"result":[{"id":"1","pattern":"b.example.com/*","script":"worker"},{"id":"2","pattern":"other.example.com/*","script":"other"}]}`,
+				"/zones/z2/workers/routes":                           `{"success":true,"result":[{"id":"3","pattern":"a.example.com/*","script":"worker"}]}`,
+			}
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				response, ok := responses[r.URL.Path]
+				if !ok || r.URL.Path == tt.failing {
+					w.WriteHeader(http.StatusInternalServerError)
+					_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"failed"}]}`))
+					return
+				}
+				_, _ = w.Write([]byte(response))
+			}), nil)
+
+			description, err := c.Describe(context.Background(), "worker", tt.zoneIDs...)
+			var describeErr *DescribeError
+			switch {
+			case tt.wantErr == nil:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case errors.As(tt.wantErr, &describeErr):
+				if !errors.As(err, &describeErr) {
+					t.Fatalf("Describe() error = %v, want a *DescribeError", err)
+				}
+				if _, ok := describeErr.Failed[tt.wantFailed]; !ok || len(describeErr.Failed) != 1 {
+					t.Fatalf("failed parts = %v, want only %s", describeErr.Failed, tt.wantFailed)
+				}
+			default:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Describe() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if len(description.Routes) != len(tt.wantRoutes) {
+				t.Fatalf("routes = %+v, want %v", description.Routes, tt.wantRoutes)
+			}
+			for i, route := range description.Routes {
+				if route.Pattern != tt.wantRoutes[i] {
+					t.Errorf("route %d = %q, want %q", i, route.Pattern, tt.wantRoutes[i])
+				}
+			}
+			if len(description.Domains) != tt.wantDomains {
+				t.Errorf("domains = %+v, want %d", description.Domains, tt.wantDomains)
+			}
+			if tt.failing == "" && (len(description.Bindings) != 1 || len(description.Schedules) != 1 || !description.SubdomainEnabled) {
+				t.Errorf("incomplete description: %+v", description)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"sort"
+	"strings"
+)
+
+type BindingChange struct {
+	Name    string
+	Current bindings.Worker
+	Desired bindings.Worker
+}
+
+type BindingDiff struct {
+	Added   []bindings.Worker
+	Removed []bindings.Worker
+	Changed []BindingChange
+}
+
+func (d *BindingDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func (d *BindingDiff) String() string {
+	var b strings.Builder
+	for _, w := range d.Added {
+		fmt.Fprintf(&b, "+ %s (%s)\n", w.Name, w.Type)
+	}
+	for _, w := range d.Removed {
+		fmt.Fprintf(&b, "- %s (%s)\n", w.Name, w.Type)
+	}
+	for _, change := range d.Changed {
+		fmt.Fprintf(&b, "~ %s (%s)", change.Name, change.Desired.Type)
+		for _, field := range changedFields(change.Current, change.Desired) {
+			fmt.Fprintf(&b, " %s", field)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DiffBindings compares the currently deployed bindings against the desired ones,
+// keyed by binding name. A binding whose type differs is reported as changed.
+// Secret values are never returned by the API, so secret bindings are only
+// compared by name and type.
+func DiffBindings(current []bindings.Worker, desired []bindings.Worker) (*BindingDiff, error) {
+	currentByName, err := bindingsByName(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current bindings: %w", err)
+	}
+	desiredByName, err := bindingsByName(desired)
+	if err != nil {
+		return nil, fmt.Errorf("invalid desired bindings: %w", err)
+	}
+
+	diff := new(BindingDiff)
+	for _, name := range sortedBindingNames(desiredByName) {
+		d := desiredByName[name]
+		c, ok := currentByName[name]
+		if !ok {
+			diff.Added = append(diff.Added, d)
+			continue
+		}
+		if len(changedFields(c, d)) > 0 {
+			diff.Changed = append(diff.Changed, BindingChange{Name: name, Current: c, Desired: d})
+		}
+	}
+	for _, name := range sortedBindingNames(currentByName) {
+		if _, ok := desiredByName[name]; !ok {
+			diff.Removed = append(diff.Removed, currentByName[name])
+		}
+	}
+
+	return diff, nil
+}
+
+func bindingsByName(workers []bindings.Worker) (map[string]bindings.Worker, error) {
+	byName := make(map[string]bindings.Worker, len(workers))
+	for _, w := range workers {
+		if _, ok := byName[w.Name]; ok {
+			return nil, fmt.Errorf("%w %q", ErrDuplicateBinding, w.Name)
+		}
+		byName[w.Name] = w
+	}
+	return byName, nil
+}
+
+func sortedBindingNames(byName map[string]bindings.Worker) []string {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func changedFields(current bindings.Worker, desired bindings.Worker) []string {
+	var fields []string
+	if current.Type != desired.Type {
+		return []string{fmt.Sprintf("type: %s -> %s", current.Type, desired.Type)}
+	}
+	if current.Type == "secret_text" {
+		return nil
+	}
+	if current.Part != desired.Part {
+		fields = append(fields, fmt.Sprintf("part: %s -> %s", current.Part, desired.Part))
+	}
+	if current.Text != desired.Text {
+		fields = append(fields, "text changed")
+	}
+	if current.NamespaceID != desired.NamespaceID {
+		fields = append(fields, fmt.Sprintf("namespace_id: %s -> %s", current.NamespaceID, desired.NamespaceID))
+	}
+	if current.Service != desired.Service {
+		fields = append(fields, fmt.Sprintf("service: %s -> %s", current.Service, desired.Service))
+	}
+	if current.ID != desired.ID {
+		fields = append(fields, fmt.Sprintf("id: %s -> %s", current.ID, desired.ID))
+	}
+	if current.ClassName != desired.ClassName {
+		fields = append(fields, fmt.Sprintf("class_name: %s -> %s", current.ClassName, desired.ClassName))
+	}
+	if current.Namespace != desired.Namespace {
+		fields = append(fields, fmt.Sprintf("namespace: %s -> %s", current.Namespace, desired.Namespace))
+	}
+	if current.StoreID != desired.StoreID {
+		fields = append(fields, fmt.Sprintf("store_id: %s -> %s", current.StoreID, desired.StoreID))
+	}
+	if current.SecretName != desired.SecretName {
+		fields = append(fields, fmt.Sprintf("secret_name: %s -> %s", current.SecretName, desired.SecretName))
+	}
+	if outboundKey(current.Outbound) != outboundKey(desired.Outbound) {
+		fields = append(fields, "outbound changed")
+	}
+	currentSimple, desiredSimple := bindings.RateLimitSimple{}, bindings.RateLimitSimple{}
+	if current.Simple != nil {
+		currentSimple = *current.Simple
+	}
+	if desired.Simple != nil {
+		desiredSimple = *desired.Simple
+	}
+	if currentSimple != desiredSimple {
+		fields = append(fields, fmt.Sprintf("simple: %d/%ds -> %d/%ds", currentSimple.Limit, currentSimple.Period, desiredSimple.Limit, desiredSimple.Period))
+	}
+	return fields
+}
+
+// outboundKey compares outbound blocks by their JSON, ignoring ExpectedParams
+// which is never sent to Cloudflare
+func outboundKey(outbound *bindings.Outbound) string {
+	if outbound == nil {
+		return ""
+	}
+	key, _ := json.Marshal(outbound)
+	return string(key)
+}
@@ -0,0 +1,74 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const acceptEncoding = "gzip, deflate"
+
+// decodeResponseBody replaces a gzip or deflate encoded response body with one that
+// decodes it, so callers always read plain JSON. It mirrors what http.Transport
+// does for the requests it compresses itself, which it skips once the request
+// has an Accept-Encoding header or the transport is not an *http.Transport.
+func decodeResponseBody(resp *http.Response) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding != "gzip" && encoding != "deflate" {
+		return
+	}
+
+	resp.Body = &decodedBody{body: resp.Body, encoding: encoding}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+}
+
+// decodedBody creates its decoder on the first Read, as creating one reads the
+// stream header and an empty body has none
+type decodedBody struct {
+	body     io.ReadCloser
+	encoding string
+	reader   io.ReadCloser
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.reader == nil && b.err == nil {
+		if b.encoding == "gzip" {
+			b.reader, b.err = gzip.NewReader(b.body)
+		} else {
+			b.reader, b.err = zlib.NewReader(b.body)
+		}
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.reader.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if b.reader != nil {
+		_ = b.reader.Close()
+	}
+	return b.body.Close()
+}
@@ -3,6 +3,7 @@ module github.com/loopholelabs/cloudflare
 go 1.19
 
 require (
+	github.com/coder/websocket v1.8.13
 	github.com/rs/zerolog v1.29.1
 	github.com/spf13/pflag v1.0.5
 )
@@ -0,0 +1,142 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// hedgedTransport sends a second copy of a GET request if the first has not
+// responded within delay, using whichever responds first and canceling the other.
+// Other methods are never hedged, as sending them twice could apply them twice.
+// The hedge waits for limiter's rate and holds one of its concurrency slots of its
+// own, so hedging never exceeds the limiter's budget.
+type hedgedTransport struct {
+	base    http.RoundTripper
+	delay   time.Duration
+	limiter *Limiter
+}
+
+type hedgedResult struct {
+	index   int
+	resp    *http.Response
+	err     error
+	release func()
+}
+
+func (t *hedgedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base.RoundTrip(req)
+	}
+
+	results := make(chan hedgedResult, 2)
+	var cancels []context.CancelFunc
+	send := func() {
+		ctx, cancel := context.WithCancel(req.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			release := func() {}
+			if index > 0 && t.limiter != nil {
+				if err := t.limiter.Wait(ctx); err != nil {
+					results <- hedgedResult{index: index, err: err, release: release}
+					return
+				}
+				acquired, err := t.limiter.Acquire(ctx)
+				if err != nil {
+					results <- hedgedResult{index: index, err: err, release: release}
+					return
+				}
+				release = acquired
+			}
+			resp, err := t.base.RoundTrip(req.Clone(ctx))
+			if err != nil {
+				release()
+			}
+			results <- hedgedResult{index: index, resp: resp, err: err, release: release}
+		}()
+	}
+
+	send()
+	timer := time.NewTimer(t.delay)
+	defer timer.Stop()
+
+	var firstErr error
+	for received := 0; received < len(cancels); {
+		select {
+		case <-timer.C:
+			send()
+		case result := <-results:
+			received++
+			if result.err == nil {
+				for i, cancel := range cancels {
+					if i != result.index {
+						cancel()
+					}
+				}
+				if received < len(cancels) {
+					go discardHedged(results, len(cancels)-received)
+				}
+				cancel, release := cancels[result.index], result.release
+				result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: func() {
+					cancel()
+					release()
+				}}
+				return result.resp, nil
+			}
+			cancels[result.index]()
+			if firstErr == nil {
+				firstErr = result.err
+			}
+			// the first request failed before the hedge was sent, so there is
+			// nothing left to wait for and retrying is left to AuthTransport
+			if len(cancels) == 1 {
+				return nil, firstErr
+			}
+		}
+	}
+
+	return nil, firstErr
+}
+
+// discardHedged closes the responses of the hedged requests that lost
+func discardHedged(results <-chan hedgedResult, n int) {
+	for i := 0; i < n; i++ {
+		if result := <-results; result.err == nil {
+			_ = result.resp.Body.Close()
+			result.release()
+		}
+	}
+}
+
+// cancelOnClose releases the context and limiter slot of the winning hedged request
+// once its body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.cancel)
+	return err
+}
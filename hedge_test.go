@@ -0,0 +1,149 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgedTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		slowFirst    bool
+		wantRequests int32
+		wantBody     string
+		wantCanceled bool
+	}{
+		{name: "fast get is not hedged", method: "GET", wantRequests: 1, wantBody: "1"},
+		{name: "slow get is hedged", method: "GET", slowFirst: true, wantRequests: 2, wantBody: "2", wantCanceled: true},
+		{name: "slow post is not hedged", method: "POST", slowFirst: true, wantRequests: 1, wantBody: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			canceled := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&requests, 1)
+				if n == 1 && tt.slowFirst {
+					select {
+					case <-r.Context().Done():
+						close(canceled)
+						return
+					case <-time.After(200 * time.Millisecond):
+					}
+				}
+				_, _ = w.Write([]byte{byte('0' + n)})
+			}))
+			t.Cleanup(server.Close)
+
+			transport := &hedgedTransport{base: http.DefaultTransport.(*http.Transport).Clone(), delay: 20 * time.Millisecond}
+			req, err := http.NewRequest(tt.method, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("response from request %s, want %s", body, tt.wantBody)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			if tt.wantCanceled {
+				select {
+				case <-canceled:
+				case <-time.After(time.Second):
+					t.Errorf("the losing request was not canceled")
+				}
+			}
+		})
+	}
+}
+
+func TestHedgedTransportLimiter(t *testing.T) {
+	tests := []struct {
+		name         string
+		concurrency  int
+		wantRequests int32
+	}{
+		{name: "full limiter holds back the hedge", concurrency: 1, wantRequests: 1},
+		{name: "hedge takes a free slot", concurrency: 2, wantRequests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(100 * time.Millisecond):
+				}
+				_, _ = w.Write([]byte("ok"))
+			}))
+			t.Cleanup(server.Close)
+
+			limiter := NewLimiter(0, 1, tt.concurrency)
+			// the slot of the original request, as held by the client for the whole call
+			release, err := limiter.Acquire(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport := &hedgedTransport{base: http.DefaultTransport.(*http.Transport).Clone(), delay: 10 * time.Millisecond, limiter: limiter}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			release()
+
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+			// every slot, including one taken by the hedge, must be free again
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			for i := 0; i < tt.concurrency; i++ {
+				acquired, err := limiter.Acquire(ctx)
+				if err != nil {
+					t.Fatalf("limiter slot was not released: %v", err)
+				}
+				defer acquired()
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+)
+
+// deployLock is a channel with a single slot so that waiting for it can be canceled
+// with ctx, and the number of callers holding or waiting for it
+type deployLock struct {
+	ch   chan struct{}
+	refs int
+}
+
+// lockIdentifier serializes deploys of the same worker within this process when
+// Options.SerializeDeploys is set, returning a function that releases the lock.
+// Locks are removed once no caller holds or waits for them, so deploying many
+// different workers doesn't grow the client's memory.
+func (c *Cloudflare) lockIdentifier(ctx context.Context, identifier string) (func(), error) {
+	if !c.options.SerializeDeploys {
+		return func() {}, nil
+	}
+
+	c.deployLocksMu.Lock()
+	if c.deployLocks == nil {
+		c.deployLocks = make(map[string]*deployLock)
+	}
+	lock, ok := c.deployLocks[identifier]
+	if !ok {
+		lock = &deployLock{ch: make(chan struct{}, 1)}
+		c.deployLocks[identifier] = lock
+	}
+	lock.refs++
+	c.deployLocksMu.Unlock()
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			c.releaseLock(identifier, lock)
+		}, nil
+	case <-ctx.Done():
+		c.releaseLock(identifier, lock)
+		return nil, ctx.Err()
+	}
+}
+
+// releaseLock drops a reference to lock, removing it once it is unused
+func (c *Cloudflare) releaseLock(identifier string, lock *deployLock) {
+	c.deployLocksMu.Lock()
+	defer c.deployLocksMu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(c.deployLocks, identifier)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// inFlightRecorder records the highest number of concurrent requests per worker
+type inFlightRecorder struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+	max      map[string]int
+}
+
+func (r *inFlightRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, _ = io.Copy(io.Discard, req.Body)
+	identifier := strings.TrimPrefix(req.URL.Path, "/accounts/account/workers/scripts/")
+	r.mu.Lock()
+	r.inFlight[identifier]++
+	if r.inFlight[identifier] > r.max[identifier] {
+		r.max[identifier] = r.inFlight[identifier]
+	}
+	r.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	r.mu.Lock()
+	r.inFlight[identifier]--
+	r.mu.Unlock()
+	_, _ = w.Write([]byte(`{"success":true,"result":{}}`))
+}
+
+func TestSerializeDeploys(t *testing.T) {
+	tests := []struct {
+		name        string
+		identifiers []string
+		wantMax     int
+	}{
+		{name: "same worker", identifiers: []string{"worker", "worker", "worker", "worker", "worker", "worker"}, wantMax: 1},
+		{name: "different workers", identifiers: []string{"one", "two", "one", "two"}, wantMax: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &inFlightRecorder{inFlight: make(map[string]int), max: make(map[string]int)}
+			c, _ := newTestClient(t, recorder, func(options *Options) {
+				options.SerializeDeploys = true
+			})
+
+			var wg sync.WaitGroup
+			errs := make(chan error, len(tt.identifiers)*2)
+			for _, identifier := range tt.identifiers {
+				identifier := identifier
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					_, err := c.Upload(context.Background(), &UploadInput{
+						Identifier:    identifier,
+						WrapperScript: []byte("export default {}"),
+						MainModule:    true,
+						SkipSubdomain: true,
+					})
+					errs <- err
+				}()
+				go func() {
+					defer wg.Done()
+					errs <- c.Delete(context.Background(), identifier)
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Error(err)
+				}
+			}
+
+			for identifier, max := range recorder.max {
+				if max != tt.wantMax {
+					t.Errorf("%d concurrent deploys of %s, want %d", max, identifier, tt.wantMax)
+				}
+			}
+			c.deployLocksMu.Lock()
+			defer c.deployLocksMu.Unlock()
+			if len(c.deployLocks) != 0 {
+				t.Errorf("%d locks left after all deploys finished, want 0", len(c.deployLocks))
+			}
+		})
+	}
+}
+
+func TestLockIdentifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		serialize bool
+		second    string
+		wantErr   error
+	}{
+		{name: "same worker waits", serialize: true, second: "worker", wantErr: context.DeadlineExceeded},
+		{name: "other worker does not wait", serialize: true, second: "other"},
+		{name: "disabled", serialize: false, second: "worker"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, http.NotFoundHandler(), func(options *Options) {
+				options.SerializeDeploys = tt.serialize
+			})
+			unlock, err := c.lockIdentifier(context.Background(), "worker")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			second, err := c.lockIdentifier(ctx, tt.second)
+			if err != tt.wantErr {
+				t.Fatalf("lockIdentifier() error = %v, want %v", err, tt.wantErr)
+			}
+			if second != nil {
+				second()
+			}
+
+			unlock()
+			again, err := c.lockIdentifier(context.Background(), "worker")
+			if err != nil {
+				t.Fatalf("lock was not released: %v", err)
+			}
+			again()
+
+			c.deployLocksMu.Lock()
+			defer c.deployLocksMu.Unlock()
+			if len(c.deployLocks) != 0 {
+				t.Errorf("%d locks left after release, want 0", len(c.deployLocks))
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// multipartBody is a multipart upload body that is streamed through a pipe each
+// time it is opened, rather than being assembled in memory up front
+type multipartBody struct {
+	parts       []bindings.Part
+	boundary    string
+	contentType string
+	length      int64
+}
+
+// newMultipartBody prepares a body of parts followed by the metadata part. The body
+// is first written to a counting writer, which catches invalid parts before
+// anything is sent and gives the exact content length.
+func newMultipartBody(parts []bindings.Part, metadataJSON []byte) (*multipartBody, error) {
+	all := make([]bindings.Part, 0, len(parts)+1)
+	all = append(all, parts...)
+	all = append(all, bindings.Part{
+		FieldName:   "metadata",
+		FileName:    "metadata.json",
+		ContentType: "application/json",
+		Content:     metadataJSON,
+	})
+
+	counter := new(countingWriter)
+	writer := multipart.NewWriter(counter)
+	err := writeMultipart(writer, all)
+	if err != nil {
+		return nil, err
+	}
+
+	return &multipartBody{
+		parts:       all,
+		boundary:    writer.Boundary(),
+		contentType: writer.FormDataContentType(),
+		length:      counter.n,
+	}, nil
+}
+
+// open streams the body through a pipe. If writing to the pipe fails, the pipe is
+// closed with the error identifying the failed part, so the request fails instead
+// of sending a truncated body.
+func (b *multipartBody) open() io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		writer := multipart.NewWriter(w)
+		err := writer.SetBoundary(b.boundary)
+		if err == nil {
+			err = writeMultipart(writer, b.parts)
+		}
+		_ = w.CloseWithError(err)
+	}()
+	return r
+}
+
+// newMultipartRequest creates an authorized request streaming parts and the
+// metadata, reporting progress to any ProgressFunc in ctx and applying
+// Options.BodyTransform. GetBody reopens the body so the request can be retried.
+func (c *Cloudflare) newMultipartRequest(ctx context.Context, method string, requestURL string, parts []bindings.Part, metadataJSON []byte) (*http.Request, error) {
+	body, err := newMultipartBody(parts, metadataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := progressFromContext(ctx)
+	open := func() (io.ReadCloser, error) {
+		reader := withProgressReader(body.open(), body.length, progress)
+		if c.options.BodyTransform == nil {
+			return reader, nil
+		}
+		transformed, transformHeader, err := c.options.BodyTransform(reader)
+		if err != nil {
+			_ = reader.Close()
+			return nil, fmt.Errorf("error transforming request body: %w", err)
+		}
+		return &transformedBody{Reader: transformed, Closer: reader, header: transformHeader}, nil
+	}
+
+	reader, err := open()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		_ = reader.Close()
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.GetBody = open
+	req.ContentLength = body.length
+	if c.options.BodyTransform != nil {
+		req.ContentLength = -1
+	}
+	req.Header.Set("Content-Type", body.contentType)
+	if transformed, ok := reader.(*transformedBody); ok {
+		transformed.applyHeader(req)
+	}
+	c.authorize(req)
+	return req, nil
+}
+
+// transformedBody reads from the reader returned by Options.BodyTransform and
+// closes the original body. It keeps the headers returned along with it, which
+// AuthTransport reapplies whenever GetBody transforms the body again for a retry.
+type transformedBody struct {
+	io.Reader
+	io.Closer
+	header http.Header
+}
+
+func (b *transformedBody) applyHeader(req *http.Request) {
+	for key, values := range b.header {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+}
+
+func writeMultipart(writer *multipart.Writer, parts []bindings.Part) error {
+	for _, part := range parts {
+		err := addPart(writer, part)
+		if err != nil {
+			return fmt.Errorf("error adding part %s to multipart request: %w", part.FormFieldName(), err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return fmt.Errorf("error closing multipart writer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestMultipartRequestBodyTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform bool
+		failures  int
+	}{
+		{name: "plain", failures: 0},
+		{name: "plain retried", failures: 2},
+		{name: "transformed", transform: true, failures: 0},
+		{name: "transformed retried", transform: true, failures: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received int64
+			var signatures [][]string
+			c, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("error reading body: %v", err)
+				}
+				mu.Lock()
+				received += int64(len(body))
+				signatures = append(signatures, r.Header.Values("X-Signature"))
+				attempt := len(signatures)
+				mu.Unlock()
+				if attempt <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}), func(options *Options) {
+				options.MaxRetries = tt.failures
+				if tt.transform {
+					calls := 0
+					options.BodyTransform = func(r io.Reader) (io.Reader, http.Header, error) {
+						calls++
+						return io.MultiReader(bytes.NewReader([]byte("signed:")), r), http.Header{"x-signature": {fmt.Sprint(calls)}}, nil
+					}
+				}
+			})
+
+			ctx, stats := withRequestStats(context.Background())
+			parts := []bindings.Part{{FieldName: "worker.js", ContentType: "application/javascript+module", Content: []byte("export default {}")}}
+			req, err := c.newMultipartRequest(ctx, "PUT", server.URL+"/script", parts, []byte(`{}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := c.do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+
+			if len(signatures) != tt.failures+1 {
+				t.Fatalf("attempts = %d, want %d", len(signatures), tt.failures+1)
+			}
+			for i, signature := range signatures {
+				var want []string
+				if tt.transform {
+					want = []string{fmt.Sprint(i + 1)}
+				}
+				if fmt.Sprint(signature) != fmt.Sprint(want) {
+					t.Errorf("attempt %d X-Signature = %v, want %v", i+1, signature, want)
+				}
+			}
+			if stats.BytesSent() != received {
+				t.Errorf("BytesSent() = %d, want %d", stats.BytesSent(), received)
+			}
+			if stats.Attempts() != tt.failures+1 {
+				t.Errorf("Attempts() = %d, want %d", stats.Attempts(), tt.failures+1)
+			}
+		})
+	}
+}
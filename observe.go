@@ -0,0 +1,115 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"net/http"
+	"time"
+)
+
+type operationKey struct{}
+
+// Observation describes a single HTTP request made to the Cloudflare API, and is
+// passed to Options.ObserveFunc once the response headers have been received
+type Observation struct {
+	// Name is the client method that made the request, such as "PutSecret". It
+	// never contains identifiers, so unlike Operation it can be used as a metric
+	// label.
+	Name string
+
+	// Operation describes the request for logs, such as "uploading worker"
+	Operation  string
+	Method     string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+
+	// Attempts is the number of HTTP attempts made, more than one if the request
+	// was retried
+	Attempts int
+
+	// Fields holds the values of Options.ContextKeys found in the request's context
+	Fields map[string]interface{}
+}
+
+// operation identifies the API call that a request belongs to
+type operation struct {
+	name   string
+	action string
+}
+
+// withOperation records the API call that requests made with ctx belong to. name
+// is the client method making the call, such as "PutSecret", and action describes
+// the call for errors and logs, such as "updating worker secret".
+func withOperation(ctx context.Context, name string, action string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation{name: name, action: action})
+}
+
+func operationFromContext(ctx context.Context) operation {
+	op, _ := ctx.Value(operationKey{}).(operation)
+	return op
+}
+
+func (c *Cloudflare) observe(req *http.Request, resp *http.Response, err error, start time.Time, stats *requestStats) {
+	if c.options.ObserveFunc == nil {
+		return
+	}
+	op := operationFromContext(req.Context())
+	observation := Observation{
+		Name:      op.name,
+		Operation: op.action,
+		Method:    req.Method,
+		Duration:  time.Since(start),
+		Err:       err,
+		Attempts:  stats.Attempts(),
+		Fields:    c.contextFields(req.Context()),
+	}
+	if resp != nil {
+		observation.StatusCode = resp.StatusCode
+	}
+	c.options.ObserveFunc(observation)
+}
+
+// contextFields returns the values of Options.ContextKeys set in ctx, or nil if
+// there are none
+func (c *Cloudflare) contextFields(ctx context.Context) map[string]interface{} {
+	if len(c.options.ContextKeys) == 0 {
+		return nil
+	}
+	var fields map[string]interface{}
+	for name, key := range c.options.ContextKeys {
+		if value := ctx.Value(key); value != nil {
+			if fields == nil {
+				fields = make(map[string]interface{}, len(c.options.ContextKeys))
+			}
+			fields[name] = value
+		}
+	}
+	return fields
+}
+
+// log returns the client's logger with the context fields of ctx added
+func (c *Cloudflare) log(ctx context.Context) *zerolog.Logger {
+	fields := c.contextFields(ctx)
+	if fields == nil {
+		return c.logger
+	}
+	l := c.logger.With().Fields(fields).Logger()
+	return &l
+}
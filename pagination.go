@@ -0,0 +1,65 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/url"
+	"strconv"
+)
+
+// pageFunc fetches page from pageURL, returning the number of results on the page,
+// the page's result_info, and whether paging should stop early. Results from a
+// page for which wrongPage reports true should be skipped.
+type pageFunc func(ctx context.Context, page int, pageURL string) (count int, info *models.ResultInfo, stop bool, err error)
+
+// paginate fetches requestURL page by page with perPage results per page until
+// fetch stops early, the page reported by total_pages is reached or a page is
+// empty. When total_pages is unknown a short page also ends paging, and so does a
+// response for a different page than the one requested, since the server is then
+// ignoring the page parameter. Responses without result_info are treated as
+// unpaginated and end paging after the first request.
+func (c *Cloudflare) paginate(ctx context.Context, requestURL string, query url.Values, perPage int, fetch pageFunc) error {
+	for page := 1; ; page++ {
+		pageQuery := url.Values{}
+		for key, values := range query {
+			pageQuery[key] = values
+		}
+		pageQuery.Set("page", strconv.Itoa(page))
+		pageQuery.Set("per_page", strconv.Itoa(perPage))
+
+		count, info, stop, err := fetch(ctx, page, requestURL+"?"+pageQuery.Encode())
+		if err != nil {
+			return err
+		}
+		if stop || info == nil || count == 0 || wrongPage(info, page) {
+			return nil
+		}
+		if info.TotalPages > 0 && page >= info.TotalPages {
+			return nil
+		}
+		if info.TotalPages == 0 && count < perPage {
+			return nil
+		}
+	}
+}
+
+// wrongPage reports whether info describes a page other than the requested one
+func wrongPage(info *models.ResultInfo, page int) bool {
+	return info != nil && info.Page != 0 && info.Page != page
+}
@@ -0,0 +1,141 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name       string
+		pages      [][]string
+		totalPages int
+		noInfo     bool
+		ignorePage bool
+		stopAt     string
+		want       []string
+		requests   int64
+	}{
+		{
+			name:       "short pages before total_pages",
+			pages:      [][]string{{"a", "b"}, {"c"}, {"d"}},
+			totalPages: 3,
+			want:       []string{"a", "b", "c", "d"},
+			requests:   3,
+		},
+		{
+			name:     "empty page without total_pages",
+			pages:    [][]string{{"a", "b"}, {"c", "d"}, {}},
+			want:     []string{"a", "b", "c", "d"},
+			requests: 3,
+		},
+		{
+			name:     "short page without total_pages",
+			pages:    [][]string{{"a", "b"}, {"c"}, {"d"}},
+			want:     []string{"a", "b", "c"},
+			requests: 2,
+		},
+		{
+			name:       "server ignores page",
+			pages:      [][]string{{"a", "b"}, {"c", "d"}},
+			ignorePage: true,
+			want:       []string{"a", "b"},
+			requests:   2,
+		},
+		{
+			name:     "unpaginated response",
+			pages:    [][]string{{"a"}, {"b"}},
+			noInfo:   true,
+			want:     []string{"a"},
+			requests: 1,
+		},
+		{
+			name:       "stopped early",
+			pages:      [][]string{{"a", "b"}, {"c"}},
+			totalPages: 2,
+			stopAt:     "b",
+			want:       []string{"a", "b"},
+			requests:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int64
+			c, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt64(&requests, 1)
+				if r.URL.Query().Get("per_page") != "2" {
+					t.Errorf("per_page = %q, want %q", r.URL.Query().Get("per_page"), "2")
+				}
+				page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+				if tt.ignorePage {
+					page = 1
+				}
+				res := models.ScriptsResponse{Response: models.Response{Success: true}}
+				if page <= len(tt.pages) {
+					for _, id := range tt.pages[page-1] {
+						res.Result = append(res.Result, models.Script{ID: id})
+					}
+				}
+				if !tt.noInfo {
+					res.ResultInfo = &models.ResultInfo{Page: page, PerPage: 2, Count: len(res.Result), TotalPages: tt.totalPages}
+				}
+				_ = json.NewEncoder(w).Encode(res)
+			}), nil)
+
+			var got []string
+			err := c.paginate(context.Background(), server.URL+"/scripts", nil, 2, func(ctx context.Context, page int, pageURL string) (int, *models.ResultInfo, bool, error) {
+				res := new(models.ScriptsResponse)
+				err := c.doJSON(ctx, "GET", pageURL, nil, res, "ListFunctions", "listing workers")
+				if err != nil {
+					return 0, nil, false, err
+				}
+				if wrongPage(res.ResultInfo, page) {
+					return 0, res.ResultInfo, true, nil
+				}
+				for _, script := range res.Result {
+					got = append(got, script.ID)
+					if script.ID == tt.stopAt {
+						return len(res.Result), res.ResultInfo, true, nil
+					}
+				}
+				return len(res.Result), res.ResultInfo, false, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("results = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("results = %v, want %v", got, tt.want)
+				}
+			}
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+		})
+	}
+}
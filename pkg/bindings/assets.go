@@ -0,0 +1,76 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrAssetsJWTRequired       = errors.New("assets upload jwt is required")
+	ErrInvalidHTMLHandling     = errors.New("invalid assets html handling")
+	ErrInvalidNotFoundHandling = errors.New("invalid assets not found handling")
+)
+
+const (
+	HTMLHandlingAutoTrailingSlash  = "auto-trailing-slash"
+	HTMLHandlingForceTrailingSlash = "force-trailing-slash"
+	HTMLHandlingDropTrailingSlash  = "drop-trailing-slash"
+	HTMLHandlingNone               = "none"
+
+	NotFoundHandlingNone                  = "none"
+	NotFoundHandling404Page               = "404-page"
+	NotFoundHandlingSinglePageApplication = "single-page-application"
+)
+
+// Assets references static assets already uploaded through an assets upload
+// session, using the completion JWT returned by that session
+type Assets struct {
+	JWT    string        `json:"jwt"`
+	Config *AssetsConfig `json:"config,omitempty"`
+}
+
+type AssetsConfig struct {
+	HTMLHandling     string `json:"html_handling,omitempty"`
+	NotFoundHandling string `json:"not_found_handling,omitempty"`
+	RunWorkerFirst   bool   `json:"run_worker_first,omitempty"`
+}
+
+func (a *Assets) Validate() error {
+	if a.JWT == "" {
+		return ErrAssetsJWTRequired
+	}
+
+	if a.Config == nil {
+		return nil
+	}
+
+	switch a.Config.HTMLHandling {
+	case "", HTMLHandlingAutoTrailingSlash, HTMLHandlingForceTrailingSlash, HTMLHandlingDropTrailingSlash, HTMLHandlingNone:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidHTMLHandling, a.Config.HTMLHandling)
+	}
+
+	switch a.Config.NotFoundHandling {
+	case "", NotFoundHandlingNone, NotFoundHandling404Page, NotFoundHandlingSinglePageApplication:
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidNotFoundHandling, a.Config.NotFoundHandling)
+	}
+
+	return nil
+}
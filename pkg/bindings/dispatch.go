@@ -0,0 +1,125 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrDispatchNameRequired      = errors.New("dispatch namespace binding name is required")
+	ErrDispatchNamespaceRequired = errors.New("dispatch namespace is required")
+	ErrOutboundServiceRequired   = errors.New("outbound worker service is required")
+	ErrInvalidOutboundParams     = errors.New("invalid outbound worker params")
+)
+
+// DispatchNamespace binds a Workers for Platforms dispatch namespace. If Outbound
+// is set, fetches from the namespace's user workers are routed through the
+// outbound worker, which receives Params from the dispatcher with every request.
+type DispatchNamespace struct {
+	Name      string
+	Namespace string
+	Outbound  *Outbound
+	Enabled   *bool
+}
+
+type Outbound struct {
+	Worker OutboundWorker  `json:"worker"`
+	Params []OutboundParam `json:"params,omitempty"`
+
+	// ExpectedParams, if known, are the params the outbound worker reads, and
+	// Params must name exactly these
+	ExpectedParams []string `json:"-"`
+}
+
+type OutboundWorker struct {
+	Service     string `json:"service"`
+	Environment string `json:"environment,omitempty"`
+}
+
+type OutboundParam struct {
+	Name string `json:"name"`
+}
+
+func (d *DispatchNamespace) Validate() error {
+	if d.Name == "" {
+		return ErrDispatchNameRequired
+	}
+
+	if d.Namespace == "" {
+		return fmt.Errorf("%w for %q", ErrDispatchNamespaceRequired, d.Name)
+	}
+
+	if d.Outbound != nil {
+		if err := d.Outbound.Validate(); err != nil {
+			return fmt.Errorf("%w for %q", err, d.Name)
+		}
+	}
+
+	return nil
+}
+
+func (o *Outbound) Validate() error {
+	if o.Worker.Service == "" {
+		return ErrOutboundServiceRequired
+	}
+
+	params := make(map[string]struct{}, len(o.Params))
+	for _, param := range o.Params {
+		if param.Name == "" {
+			return fmt.Errorf("%w: empty param name", ErrInvalidOutboundParams)
+		}
+		if _, ok := params[param.Name]; ok {
+			return fmt.Errorf("%w: duplicate param %q", ErrInvalidOutboundParams, param.Name)
+		}
+		params[param.Name] = struct{}{}
+	}
+
+	if o.ExpectedParams != nil {
+		var missing, unexpected []string
+		expected := make(map[string]struct{}, len(o.ExpectedParams))
+		for _, name := range o.ExpectedParams {
+			expected[name] = struct{}{}
+			if _, ok := params[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		for name := range params {
+			if _, ok := expected[name]; !ok {
+				unexpected = append(unexpected, name)
+			}
+		}
+		if len(missing) > 0 || len(unexpected) > 0 {
+			sort.Strings(unexpected)
+			return fmt.Errorf("%w: missing [%s], unexpected [%s]", ErrInvalidOutboundParams, strings.Join(missing, ", "), strings.Join(unexpected, ", "))
+		}
+	}
+
+	return nil
+}
+
+func (d *DispatchNamespace) Worker() Worker {
+	return Worker{
+		Type:      "dispatch_namespace",
+		Name:      d.Name,
+		Namespace: d.Namespace,
+		Outbound:  d.Outbound,
+	}
+}
@@ -0,0 +1,47 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+// isEnabled treats an unset Enabled field as enabled, so bindings only need to set
+// it to explicitly disable themselves
+func isEnabled(enabled *bool) bool {
+	return enabled == nil || *enabled
+}
+
+func (f *File) IsEnabled() bool {
+	return isEnabled(f.Enabled)
+}
+
+func (r *RateLimit) IsEnabled() bool {
+	return isEnabled(r.Enabled)
+}
+
+func (h *Hyperdrive) IsEnabled() bool {
+	return isEnabled(h.Enabled)
+}
+
+func (d *DispatchNamespace) IsEnabled() bool {
+	return isEnabled(d.Enabled)
+}
+
+func (s *SecretsStoreBinding) IsEnabled() bool {
+	return isEnabled(s.Enabled)
+}
+
+func (w *Worker) IsEnabled() bool {
+	return isEnabled(w.Enabled)
+}
@@ -22,15 +22,55 @@ type File struct {
 	ContentType string
 	Binding     string
 	Type        string
+	Enabled     *bool
 }
 
 type Function struct {
-	Identifier string
-	Source     []byte
-	Files      []File
+	Identifier         string
+	Source             []byte
+	Files              []File
+	RateLimits         []RateLimit
+	HyperdriveBindings []Hyperdrive
+	DispatchNamespaces []DispatchNamespace
+	SecretsStore       []SecretsStoreBinding
+	Vars               map[string]string
+}
+
+// Binding is a typed binding of a Function, which is validated and added to the
+// worker's bindings when it is enabled
+type Binding interface {
+	Validate() error
+	Worker() Worker
+	IsEnabled() bool
+}
+
+// Bindings returns the typed bindings of the function in a fixed order
+func (f *Function) Bindings() []Binding {
+	all := make([]Binding, 0, len(f.RateLimits)+len(f.HyperdriveBindings)+len(f.DispatchNamespaces)+len(f.SecretsStore))
+	for i := range f.RateLimits {
+		all = append(all, &f.RateLimits[i])
+	}
+	for i := range f.HyperdriveBindings {
+		all = append(all, &f.HyperdriveBindings[i])
+	}
+	for i := range f.DispatchNamespaces {
+		all = append(all, &f.DispatchNamespaces[i])
+	}
+	for i := range f.SecretsStore {
+		all = append(all, &f.SecretsStore[i])
+	}
+	return all
 }
 
 type UploadedFunction struct {
-	Identifier string
-	Subdomain  string
+	Identifier       string
+	Subdomain        string
+	SubdomainEnabled bool
+	SubdomainError   error
+	UsageModel       string
+	UsageModelError  error
+	Manifest         *UploadManifest
+	Script           string
+	BytesSent        int64
+	Attempts         int
 }
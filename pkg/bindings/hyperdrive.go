@@ -0,0 +1,57 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrHyperdriveNameRequired = errors.New("hyperdrive binding name is required")
+	ErrInvalidHyperdriveID    = errors.New("hyperdrive config id must be a 32 character hex string")
+)
+
+type Hyperdrive struct {
+	Name    string
+	ID      string
+	Enabled *bool
+}
+
+func (h *Hyperdrive) Validate() error {
+	if h.Name == "" {
+		return ErrHyperdriveNameRequired
+	}
+
+	if len(h.ID) != 32 {
+		return fmt.Errorf("%w for %q", ErrInvalidHyperdriveID, h.Name)
+	}
+	if _, err := hex.DecodeString(h.ID); err != nil {
+		return fmt.Errorf("%w for %q", ErrInvalidHyperdriveID, h.Name)
+	}
+
+	return nil
+}
+
+func (h *Hyperdrive) Worker() Worker {
+	return Worker{
+		Type: "hyperdrive",
+		Name: h.Name,
+		ID:   h.ID,
+	}
+}
@@ -0,0 +1,66 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestHyperdrive(t *testing.T) {
+	tests := []struct {
+		name       string
+		hyperdrive Hyperdrive
+		wantErr    error
+		want       string
+	}{
+		{
+			name:       "valid",
+			hyperdrive: Hyperdrive{Name: "DB", ID: "0123456789abcdef0123456789abcdef"},
+			want:       `{"type":"hyperdrive","name":"DB","id":"0123456789abcdef0123456789abcdef"}`,
+		},
+		{
+			name:       "uppercase hex",
+			hyperdrive: Hyperdrive{Name: "DB", ID: "0123456789ABCDEF0123456789ABCDEF"},
+			want:       `{"type":"hyperdrive","name":"DB","id":"0123456789ABCDEF0123456789ABCDEF"}`,
+		},
+		{name: "missing name", hyperdrive: Hyperdrive{ID: "0123456789abcdef0123456789abcdef"}, wantErr: ErrHyperdriveNameRequired},
+		{name: "missing id", hyperdrive: Hyperdrive{Name: "DB"}, wantErr: ErrInvalidHyperdriveID},
+		{name: "short id", hyperdrive: Hyperdrive{Name: "DB", ID: "0123456789abcdef"}, wantErr: ErrInvalidHyperdriveID},
+		{name: "not hex", hyperdrive: Hyperdrive{Name: "DB", ID: "0123456789abcdef0123456789abcdeg"}, wantErr: ErrInvalidHyperdriveID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hyperdrive.Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			data, err := json.Marshal(tt.hyperdrive.Worker())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("binding = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
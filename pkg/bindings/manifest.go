@@ -0,0 +1,79 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// UploadManifest describes what an upload pushed, for audit logs. Bindings are
+// only described by name and type, so it never contains secret values.
+type UploadManifest struct {
+	Script             string            `json:"script"`
+	MainModule         string            `json:"main_module,omitempty"`
+	BodyPart           string            `json:"body_part,omitempty"`
+	Parts              []ManifestPart    `json:"parts"`
+	Bindings           []ManifestBinding `json:"bindings"`
+	CompatibilityDate  string            `json:"compatibility_date,omitempty"`
+	CompatibilityFlags []string          `json:"compatibility_flags,omitempty"`
+	Tags               []string          `json:"tags,omitempty"`
+}
+
+type ManifestPart struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+type ManifestBinding struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func NewUploadManifest(script string, parts []Part, metadata *Metadata) *UploadManifest {
+	manifest := &UploadManifest{
+		Script:             script,
+		MainModule:         metadata.MainModule,
+		BodyPart:           metadata.BodyPart,
+		Parts:              make([]ManifestPart, 0, len(parts)),
+		Bindings:           make([]ManifestBinding, 0, len(metadata.Bindings)),
+		CompatibilityDate:  metadata.CompatibilityDate,
+		CompatibilityFlags: metadata.CompatibilityFlags,
+		Tags:               metadata.Tags,
+	}
+
+	for _, part := range parts {
+		sum := sha256.Sum256(part.Content)
+		manifest.Parts = append(manifest.Parts, ManifestPart{
+			Name:        part.FormFieldName(),
+			ContentType: part.ContentType,
+			Size:        len(part.Content),
+			SHA256:      hex.EncodeToString(sum[:]),
+		})
+	}
+
+	for _, binding := range metadata.Bindings {
+		manifest.Bindings = append(manifest.Bindings, ManifestBinding{
+			Name: binding.Name,
+			Type: binding.Type,
+		})
+	}
+
+	return manifest
+}
@@ -17,6 +17,21 @@
 package bindings
 
 type Metadata struct {
-	BodyPart string   `json:"body_part"`
-	Bindings []Worker `json:"bindings"`
+	BodyPart           string         `json:"body_part,omitempty"`
+	MainModule         string         `json:"main_module,omitempty"`
+	Bindings           []Worker       `json:"bindings"`
+	KeepBindings       []string       `json:"keep_bindings,omitempty"`
+	CompatibilityDate  string         `json:"compatibility_date,omitempty"`
+	CompatibilityFlags []string       `json:"compatibility_flags,omitempty"`
+	Annotations        *Annotations   `json:"annotations,omitempty"`
+	Assets             *Assets        `json:"assets,omitempty"`
+	Tags               []string       `json:"tags,omitempty"`
+	Observability      *Observability `json:"observability,omitempty"`
+	Migrations         *Migrations    `json:"migrations,omitempty"`
+	UsageModel         string         `json:"usage_model,omitempty"`
+}
+
+type Annotations struct {
+	Message string `json:"workers/message,omitempty"`
+	Tag     string `json:"workers/tag,omitempty"`
 }
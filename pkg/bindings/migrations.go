@@ -0,0 +1,57 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMigrationTagRequired = errors.New("a migration tag is required when durable object classes change")
+	ErrInvalidRename        = errors.New("renamed durable object classes need both a from and a to class")
+)
+
+// Migrations describes the durable object class changes applied by an upload.
+// OldTag must match the tag of the worker's last applied migration, and is empty
+// for the first one.
+type Migrations struct {
+	OldTag         string         `json:"old_tag,omitempty"`
+	NewTag         string         `json:"new_tag,omitempty"`
+	NewClasses     []string       `json:"new_classes,omitempty"`
+	RenamedClasses []RenamedClass `json:"renamed_classes,omitempty"`
+	DeletedClasses []string       `json:"deleted_classes,omitempty"`
+}
+
+type RenamedClass struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (m *Migrations) Validate() error {
+	if m.NewTag == "" && (len(m.NewClasses) > 0 || len(m.RenamedClasses) > 0 || len(m.DeletedClasses) > 0) {
+		return ErrMigrationTagRequired
+	}
+
+	for _, rename := range m.RenamedClasses {
+		if rename.From == "" || rename.To == "" {
+			return fmt.Errorf("%w (got %q to %q)", ErrInvalidRename, rename.From, rename.To)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrModuleNameRequired = errors.New("module name is required")
+	ErrInvalidModuleType  = errors.New("invalid module type")
+)
+
+const (
+	// ModuleTypeESM modules are ES modules, such as bundled helpers or node
+	// compatibility polyfills
+	ModuleTypeESM      = "esm"
+	ModuleTypeCommonJS = "commonjs"
+	// ModuleTypeText modules are imported as a string, and should be used for
+	// HTML, CSS and other text files
+	ModuleTypeText = "text"
+	ModuleTypeData = "data"
+	ModuleTypeWasm = "wasm"
+	// ModuleTypePython modules are additional Python modules of a Python worker
+	ModuleTypePython = "python"
+)
+
+// Module is an additional module uploaded alongside the main module of a module
+// worker, which the main module (or another module) can import by Name.
+//
+// Modules are uploaded as parts after the main module, in the order given, with a
+// content type that tells Cloudflare the module type. They do not appear in the
+// metadata, which only names the main module in main_module.
+type Module struct {
+	Name    string
+	Type    string
+	Content []byte
+}
+
+func (m *Module) Validate() error {
+	if m.Name == "" {
+		return ErrModuleNameRequired
+	}
+
+	if _, err := m.contentType(); err != nil {
+		return fmt.Errorf("%w for %q", err, m.Name)
+	}
+
+	return nil
+}
+
+func (m *Module) contentType() (string, error) {
+	switch m.Type {
+	case ModuleTypeESM:
+		return "application/javascript+module", nil
+	case ModuleTypeCommonJS:
+		return "application/javascript", nil
+	case ModuleTypeText:
+		return "text/plain", nil
+	case ModuleTypeData:
+		return "application/octet-stream", nil
+	case ModuleTypeWasm:
+		return "application/wasm", nil
+	case ModuleTypePython:
+		return "text/x-python", nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrInvalidModuleType, m.Type)
+	}
+}
+
+func (m *Module) Part() Part {
+	contentType, _ := m.contentType()
+	return Part{
+		Name:        m.Name,
+		ContentType: contentType,
+		Content:     m.Content,
+	}
+}
@@ -0,0 +1,40 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidHeadSamplingRate = errors.New("head sampling rate must be between 0 and 1")
+)
+
+// Observability configures Workers Logs for the worker. A nil HeadSamplingRate
+// logs every request.
+type Observability struct {
+	Enabled          bool     `json:"enabled"`
+	HeadSamplingRate *float64 `json:"head_sampling_rate,omitempty"`
+}
+
+func (o *Observability) Validate() error {
+	if o.HeadSamplingRate != nil && (*o.HeadSamplingRate < 0 || *o.HeadSamplingRate > 1) {
+		return fmt.Errorf("%w (got %v)", ErrInvalidHeadSamplingRate, *o.HeadSamplingRate)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+// Part is a single part of a multipart upload. Name is used as both the form field
+// name and the filename unless FieldName or FileName are set.
+type Part struct {
+	Name        string
+	FieldName   string
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+func (p *Part) FormFieldName() string {
+	if p.FieldName != "" {
+		return p.FieldName
+	}
+	return p.Name
+}
+
+func (p *Part) FormFileName() string {
+	if p.FileName != "" {
+		return p.FileName
+	}
+	return p.Name
+}
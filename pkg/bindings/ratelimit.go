@@ -0,0 +1,74 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRateLimitNameRequired        = errors.New("rate limit binding name is required")
+	ErrRateLimitNamespaceIDRequired = errors.New("rate limit namespace id is required")
+	ErrInvalidRateLimitLimit        = errors.New("rate limit limit must be greater than zero")
+	ErrInvalidRateLimitPeriod       = errors.New("rate limit period must be 10 or 60 seconds")
+)
+
+type RateLimit struct {
+	Name        string
+	NamespaceID string
+	Limit       int
+	Period      int
+	Enabled     *bool
+}
+
+type RateLimitSimple struct {
+	Limit  int `json:"limit"`
+	Period int `json:"period"`
+}
+
+func (r *RateLimit) Validate() error {
+	if r.Name == "" {
+		return ErrRateLimitNameRequired
+	}
+
+	if r.NamespaceID == "" {
+		return fmt.Errorf("%w for %q", ErrRateLimitNamespaceIDRequired, r.Name)
+	}
+
+	if r.Limit <= 0 {
+		return fmt.Errorf("%w for %q", ErrInvalidRateLimitLimit, r.Name)
+	}
+
+	if r.Period != 10 && r.Period != 60 {
+		return fmt.Errorf("%w for %q", ErrInvalidRateLimitPeriod, r.Name)
+	}
+
+	return nil
+}
+
+func (r *RateLimit) Worker() Worker {
+	return Worker{
+		Type:        "ratelimit",
+		Name:        r.Name,
+		NamespaceID: r.NamespaceID,
+		Simple: &RateLimitSimple{
+			Limit:  r.Limit,
+			Period: r.Period,
+		},
+	}
+}
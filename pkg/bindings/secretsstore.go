@@ -0,0 +1,62 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bindings
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrSecretsStoreNameRequired       = errors.New("secrets store binding name is required")
+	ErrSecretsStoreIDRequired         = errors.New("secrets store id is required")
+	ErrSecretsStoreSecretNameRequired = errors.New("secrets store secret name is required")
+)
+
+// SecretsStoreBinding binds a secret from the account's Secrets Store, so unlike a
+// secret_text binding its value is never part of the upload
+type SecretsStoreBinding struct {
+	Name       string
+	StoreID    string
+	SecretName string
+	Enabled    *bool
+}
+
+func (s *SecretsStoreBinding) Validate() error {
+	if s.Name == "" {
+		return ErrSecretsStoreNameRequired
+	}
+
+	if s.StoreID == "" {
+		return fmt.Errorf("%w for %q", ErrSecretsStoreIDRequired, s.Name)
+	}
+
+	if s.SecretName == "" {
+		return fmt.Errorf("%w for %q", ErrSecretsStoreSecretNameRequired, s.Name)
+	}
+
+	return nil
+}
+
+func (s *SecretsStoreBinding) Worker() Worker {
+	return Worker{
+		Type:       "secrets_store_secret",
+		Name:       s.Name,
+		StoreID:    s.StoreID,
+		SecretName: s.SecretName,
+	}
+}
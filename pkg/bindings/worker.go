@@ -17,7 +17,18 @@
 package bindings
 
 type Worker struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
-	Part string `json:"part"`
+	Type        string           `json:"type"`
+	Name        string           `json:"name"`
+	Part        string           `json:"part,omitempty"`
+	Text        string           `json:"text,omitempty"`
+	NamespaceID string           `json:"namespace_id,omitempty"`
+	ID          string           `json:"id,omitempty"`
+	Service     string           `json:"service,omitempty"`
+	ClassName   string           `json:"class_name,omitempty"`
+	Namespace   string           `json:"namespace,omitempty"`
+	Outbound    *Outbound        `json:"outbound,omitempty"`
+	StoreID     string           `json:"store_id,omitempty"`
+	SecretName  string           `json:"secret_name,omitempty"`
+	Simple      *RateLimitSimple `json:"simple,omitempty"`
+	Enabled     *bool            `json:"-"`
 }
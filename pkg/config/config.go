@@ -27,23 +27,39 @@ var (
 	ErrTokenRequired              = errors.New("cloudflare token is required")
 	ErrPrefixRequired             = errors.New("cloudflare prefix is required")
 	ErrUpstreamRootDomainRequired = errors.New("cloudflare upstream root domain is required")
+	ErrZoneIDRequired             = errors.New("cloudflare zone id is required when routes or custom domains are configured")
+	ErrIncompleteClientCert       = errors.New("cloudflare client certificate and key files must be set together")
 )
 
 const (
-	DefaultDisabled = false
+	DefaultDisabled        = false
+	DefaultDialRetries     = 0
+	DefaultForceIPv4       = false
+	DefaultRedactAccountID = false
 )
 
 type Config struct {
-	Disabled           bool   `mapstructure:"disabled"`
-	UserID             string `mapstructure:"user_id"`
-	Token              string `mapstructure:"token"`
-	Prefix             string `mapstructure:"prefix"`
-	UpstreamRootDomain string `mapstructure:"upstream_root_domain"`
+	Disabled           bool     `mapstructure:"disabled"`
+	UserID             string   `mapstructure:"user_id"`
+	Token              string   `mapstructure:"token"`
+	Prefix             string   `mapstructure:"prefix"`
+	UpstreamRootDomain string   `mapstructure:"upstream_root_domain"`
+	BaseURL            string   `mapstructure:"base_url"`
+	DialRetries        int      `mapstructure:"dial_retries"`
+	ForceIPv4          bool     `mapstructure:"force_ipv4"`
+	RedactAccountID    bool     `mapstructure:"redact_account_id"`
+	ZoneID             string   `mapstructure:"zone_id"`
+	ZoneToken          string   `mapstructure:"zone_token"`
+	Routes             []string `mapstructure:"routes"`
+	CustomDomains      []string `mapstructure:"custom_domains"`
+	ClientCertFile     string   `mapstructure:"client_cert_file"`
+	ClientKeyFile      string   `mapstructure:"client_key_file"`
 }
 
 func New() *Config {
 	return &Config{
-		Disabled: DefaultDisabled,
+		Disabled:    DefaultDisabled,
+		DialRetries: DefaultDialRetries,
 	}
 }
 
@@ -64,6 +80,14 @@ func (c *Config) Validate() error {
 		if c.UpstreamRootDomain == "" {
 			return ErrUpstreamRootDomainRequired
 		}
+
+		if (len(c.Routes) > 0 || len(c.CustomDomains) > 0) && c.ZoneID == "" {
+			return ErrZoneIDRequired
+		}
+
+		if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+			return ErrIncompleteClientCert
+		}
 	}
 
 	return nil
@@ -75,14 +99,42 @@ func (c *Config) RootPersistentFlags(flags *pflag.FlagSet) {
 	flags.StringVar(&c.Token, "cloudflare-token", "", "The cloudflare token")
 	flags.StringVar(&c.Prefix, "cloudflare-prefix", "", "The cloudflare resource prefix")
 	flags.StringVar(&c.UpstreamRootDomain, "cloudflare-upstream-root-domain", "", "The cloudflare upstream root domain")
+	flags.StringVar(&c.BaseURL, "cloudflare-base-url", cloudflare.DefaultBaseURL, "The cloudflare api base url")
+	flags.IntVar(&c.DialRetries, "cloudflare-dial-retries", DefaultDialRetries, "The number of times to retry establishing a connection to cloudflare")
+	flags.BoolVar(&c.ForceIPv4, "cloudflare-force-ipv4", DefaultForceIPv4, "Only use IPv4 when connecting to cloudflare")
+	flags.BoolVar(&c.RedactAccountID, "cloudflare-redact-account-id", DefaultRedactAccountID, "Mask the cloudflare user id in log output")
+	flags.StringVar(&c.ZoneID, "cloudflare-zone-id", "", "The cloudflare zone id used for routes and custom domains")
+	flags.StringVar(&c.ZoneToken, "cloudflare-zone-token", "", "The cloudflare token used for zone-scoped operations, defaults to the cloudflare token")
+	flags.StringSliceVar(&c.Routes, "cloudflare-routes", nil, "The cloudflare worker route patterns")
+	flags.StringSliceVar(&c.CustomDomains, "cloudflare-custom-domains", nil, "The cloudflare worker custom domains")
+	flags.StringVar(&c.ClientCertFile, "cloudflare-client-cert-file", "", "The client certificate presented to a proxy in front of the cloudflare api")
+	flags.StringVar(&c.ClientKeyFile, "cloudflare-client-key-file", "", "The key of the cloudflare client certificate")
 }
 
 func (c *Config) GenerateOptions(logName string) (*cloudflare.Options, error) {
 	return &cloudflare.Options{
-		LogName:  logName,
-		Disabled: c.Disabled,
-		UserID:   c.UserID,
-		Token:    c.Token,
-		Prefix:   c.Prefix,
+		LogName:         logName,
+		Disabled:        c.Disabled,
+		UserID:          c.UserID,
+		Token:           c.Token,
+		Prefix:          c.Prefix,
+		BaseURL:         c.BaseURL,
+		DialRetries:     c.DialRetries,
+		ForceIPv4:       c.ForceIPv4,
+		RedactAccountID: c.RedactAccountID,
+		ZoneToken:       c.ZoneToken,
+		ClientCertFile:  c.ClientCertFile,
+		ClientKeyFile:   c.ClientKeyFile,
 	}, nil
 }
+
+// GenerateDeployInput returns a DeployInput for upload that creates the configured
+// routes and custom domains in the configured zone
+func (c *Config) GenerateDeployInput(upload *cloudflare.UploadInput) *cloudflare.DeployInput {
+	return &cloudflare.DeployInput{
+		Upload:        upload,
+		ZoneID:        c.ZoneID,
+		Routes:        c.Routes,
+		CustomDomains: c.CustomDomains,
+	}
+}
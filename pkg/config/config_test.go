@@ -0,0 +1,76 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"errors"
+	"github.com/loopholelabs/cloudflare"
+	"testing"
+)
+
+func validConfig() *Config {
+	return &Config{
+		UserID:             "account",
+		Token:              "token",
+		Prefix:             "prefix-",
+		UpstreamRootDomain: "example.com",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr error
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "disabled", modify: func(c *Config) { *c = Config{Disabled: true} }},
+		{name: "missing user id", modify: func(c *Config) { c.UserID = "" }, wantErr: ErrUserIDRequired},
+		{name: "missing token", modify: func(c *Config) { c.Token = "" }, wantErr: ErrTokenRequired},
+		{name: "missing prefix", modify: func(c *Config) { c.Prefix = "" }, wantErr: ErrPrefixRequired},
+		{name: "missing upstream root domain", modify: func(c *Config) { c.UpstreamRootDomain = "" }, wantErr: ErrUpstreamRootDomainRequired},
+		{name: "routes without zone", modify: func(c *Config) { c.Routes = []string{"example.com/*"} }, wantErr: ErrZoneIDRequired},
+		{name: "custom domains without zone", modify: func(c *Config) { c.CustomDomains = []string{"app.example.com"} }, wantErr: ErrZoneIDRequired},
+		{name: "client cert without key", modify: func(c *Config) { c.ClientCertFile = "cert.pem" }, wantErr: ErrIncompleteClientCert},
+		{name: "client key without cert", modify: func(c *Config) { c.ClientKeyFile = "key.pem" }, wantErr: ErrIncompleteClientCert},
+		{name: "client cert and key", modify: func(c *Config) { c.ClientCertFile = "cert.pem"; c.ClientKeyFile = "key.pem" }},
+		{name: "routes with zone", modify: func(c *Config) { c.Routes = []string{"example.com/*"}; c.ZoneID = "zone" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(c)
+			if err := c.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateDeployInput(t *testing.T) {
+	c := validConfig()
+	c.ZoneID = "zone"
+	c.Routes = []string{"example.com/*"}
+	c.CustomDomains = []string{"app.example.com"}
+
+	upload := &cloudflare.UploadInput{Identifier: "worker"}
+	input := c.GenerateDeployInput(upload)
+	if input.Upload != upload || input.ZoneID != "zone" || len(input.Routes) != 1 || len(input.CustomDomains) != 1 {
+		t.Errorf("GenerateDeployInput() = %+v", input)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidExpression = errors.New("invalid cron expression")
+)
+
+var (
+	monthNames = map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}
+	dayNames = map[string]int{
+		"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7,
+	}
+)
+
+type fieldKind int
+
+const (
+	minuteField fieldKind = iota
+	hourField
+	dayOfMonthField
+	monthField
+	dayOfWeekField
+)
+
+type fieldSpec struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var fieldSpecs = [...]fieldSpec{
+	minuteField:     {name: "minute", min: 0, max: 59},
+	hourField:       {name: "hour", min: 0, max: 23},
+	dayOfMonthField: {name: "day of month", min: 1, max: 31},
+	monthField:      {name: "month", min: 1, max: 12, names: monthNames},
+	dayOfWeekField:  {name: "day of week", min: 1, max: 7, names: dayNames},
+}
+
+// NthWeekday matches the Nth occurrence of a weekday in a month (for example "2#3")
+type NthWeekday struct {
+	Weekday int
+	N       int
+}
+
+// Schedule is a parsed cron expression using Cloudflare's cron trigger syntax,
+// where days of the week are numbered 1 (Sunday) through 7 (Saturday)
+type Schedule struct {
+	Expression string
+
+	Minutes     uint64
+	Hours       uint64
+	DaysOfMonth uint64
+	Months      uint64
+	DaysOfWeek  uint64
+
+	LastDayOfMonth       bool
+	LastWeekdayOfMonth   bool
+	NearestWeekdays      []int
+	LastWeekdaysOfMonth  []int
+	NthWeekdaysOfMonth   []NthWeekday
+	dayOfMonthRestricted bool
+	dayOfWeekRestricted  bool
+}
+
+func Validate(expression string) error {
+	_, err := Parse(expression)
+	return err
+}
+
+func Parse(expression string) (*Schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(fieldSpecs) {
+		return nil, fmt.Errorf("%w %q: expected %d fields, got %d", ErrInvalidExpression, expression, len(fieldSpecs), len(fields))
+	}
+
+	s := &Schedule{
+		Expression: expression,
+	}
+	for i, field := range fields {
+		err := s.parseField(fieldKind(i), field)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: invalid %s field %q: %s", ErrInvalidExpression, expression, fieldSpecs[i].name, field, err)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Schedule) parseField(kind fieldKind, field string) error {
+	spec := fieldSpecs[kind]
+	var bits uint64
+	for _, item := range strings.Split(strings.ToUpper(field), ",") {
+		if item == "" {
+			return errors.New("empty list item")
+		}
+
+		if handled, err := s.parseSpecial(kind, item); handled {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		b, err := parseItem(spec, item)
+		if err != nil {
+			return err
+		}
+		bits |= b
+	}
+
+	switch kind {
+	case minuteField:
+		s.Minutes = bits
+	case hourField:
+		s.Hours = bits
+	case dayOfMonthField:
+		s.DaysOfMonth = bits
+		s.dayOfMonthRestricted = field != "*"
+	case monthField:
+		s.Months = bits
+	case dayOfWeekField:
+		s.DaysOfWeek = bits
+		s.dayOfWeekRestricted = field != "*"
+	}
+
+	return nil
+}
+
+func (s *Schedule) parseSpecial(kind fieldKind, item string) (bool, error) {
+	switch kind {
+	case dayOfMonthField:
+		switch {
+		case item == "L":
+			s.LastDayOfMonth = true
+			return true, nil
+		case item == "LW":
+			s.LastWeekdayOfMonth = true
+			return true, nil
+		case strings.HasSuffix(item, "W"):
+			day, err := parseValue(fieldSpecs[kind], strings.TrimSuffix(item, "W"))
+			if err != nil {
+				return true, err
+			}
+			s.NearestWeekdays = append(s.NearestWeekdays, day)
+			return true, nil
+		}
+	case dayOfWeekField:
+		switch {
+		case strings.HasSuffix(item, "L"):
+			day, err := parseValue(fieldSpecs[kind], strings.TrimSuffix(item, "L"))
+			if err != nil {
+				return true, err
+			}
+			s.LastWeekdaysOfMonth = append(s.LastWeekdaysOfMonth, day)
+			return true, nil
+		case strings.Contains(item, "#"):
+			parts := strings.SplitN(item, "#", 2)
+			day, err := parseValue(fieldSpecs[kind], parts[0])
+			if err != nil {
+				return true, err
+			}
+			n, err := strconv.Atoi(parts[1])
+			if err != nil || n < 1 || n > 5 {
+				return true, fmt.Errorf("invalid occurrence %q, must be between 1 and 5", parts[1])
+			}
+			s.NthWeekdaysOfMonth = append(s.NthWeekdaysOfMonth, NthWeekday{Weekday: day, N: n})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func parseItem(spec fieldSpec, item string) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(item, "/")
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepPart)
+		if err != nil || step < 1 {
+			return 0, fmt.Errorf("invalid step %q", stepPart)
+		}
+	}
+
+	var start, end int
+	switch {
+	case rangePart == "*":
+		start, end = spec.min, spec.max
+	case strings.Contains(rangePart, "-"):
+		lo, hi, _ := strings.Cut(rangePart, "-")
+		var err error
+		start, err = parseValue(spec, lo)
+		if err != nil {
+			return 0, err
+		}
+		end, err = parseValue(spec, hi)
+		if err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range %q, start is after end", rangePart)
+		}
+	default:
+		var err error
+		start, err = parseValue(spec, rangePart)
+		if err != nil {
+			return 0, err
+		}
+		end = start
+		if hasStep {
+			end = spec.max
+		}
+	}
+
+	var bits uint64
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+func parseValue(spec fieldSpec, value string) (int, error) {
+	if v, ok := spec.names[value]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, spec.min, spec.max)
+	}
+	return v, nil
+}
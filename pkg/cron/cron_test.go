@@ -0,0 +1,72 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cron
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		expression string
+		valid      bool
+	}{
+		{expression: "* * * * *", valid: true},
+		{expression: "*/5 * * * *", valid: true},
+		{expression: "0 0 1 1 *", valid: true},
+		{expression: "30 12 * * MON-FRI", valid: true},
+		{expression: "0 0 * JAN,jul *", valid: true},
+		{expression: "0 9-17/2 * * *", valid: true},
+		{expression: "0 0 L * *", valid: true},
+		{expression: "0 0 LW * *", valid: true},
+		{expression: "0 0 15W * *", valid: true},
+		{expression: "0 0 * * 6L", valid: true},
+		{expression: "0 0 * * 2#3", valid: true},
+		{expression: "5/15 * * * *", valid: true},
+		{expression: "", valid: false},
+		{expression: "* * * *", valid: false},
+		{expression: "* * * * * *", valid: false},
+		{expression: "60 * * * *", valid: false},
+		{expression: "* 24 * * *", valid: false},
+		{expression: "* * 0 * *", valid: false},
+		{expression: "* * 32 * *", valid: false},
+		{expression: "* * * 13 *", valid: false},
+		{expression: "* * * * 0", valid: false},
+		{expression: "* * * * 8", valid: false},
+		{expression: "* * * * SUNDAY", valid: false},
+		{expression: "*/0 * * * *", valid: false},
+		{expression: "10-5 * * * *", valid: false},
+		{expression: "1,,2 * * * *", valid: false},
+		{expression: "* * * * 2#6", valid: false},
+		{expression: "* * * * 2#x", valid: false},
+		{expression: "* * L * L#1", valid: false},
+		{expression: "@daily", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			err := Validate(tt.expression)
+			if tt.valid && err != nil {
+				t.Errorf("Validate(%q) = %v, want nil", tt.expression, err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidExpression) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.expression, err, ErrInvalidExpression)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cron
+
+import (
+	"time"
+)
+
+// maxSearchYears bounds the search in Next, since expressions such as "0 0 30 2 *"
+// are valid but never match
+const maxSearchYears = 5
+
+// Next returns the first time after t, in UTC, at which the schedule fires. Cron
+// triggers run in UTC. The zero time is returned if the schedule never fires.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.Months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.Hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.Minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay follows the usual cron rule: when both the day of month and day of
+// week are restricted a day matching either fires, otherwise both must match
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth, dayOfWeek := s.matchesDayOfMonth(t), s.matchesDayOfWeek(t)
+	if s.dayOfMonthRestricted && s.dayOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+func (s *Schedule) matchesDayOfMonth(t time.Time) bool {
+	day := t.Day()
+	if s.DaysOfMonth&(1<<uint(day)) != 0 {
+		return true
+	}
+
+	last := daysIn(t.Year(), t.Month())
+	if s.LastDayOfMonth && day == last {
+		return true
+	}
+	if s.LastWeekdayOfMonth && day == nearestWeekday(t.Year(), t.Month(), last) {
+		return true
+	}
+	for _, target := range s.NearestWeekdays {
+		if target <= last && day == nearestWeekday(t.Year(), t.Month(), target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schedule) matchesDayOfWeek(t time.Time) bool {
+	weekday := int(t.Weekday()) + 1
+	if s.DaysOfWeek&(1<<uint(weekday)) != 0 {
+		return true
+	}
+
+	for _, last := range s.LastWeekdaysOfMonth {
+		if weekday == last && t.Day()+7 > daysIn(t.Year(), t.Month()) {
+			return true
+		}
+	}
+	for _, nth := range s.NthWeekdaysOfMonth {
+		if weekday == nth.Weekday && (t.Day()-1)/7+1 == nth.N {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday closest to day without leaving the month
+func nearestWeekday(year int, month time.Month, day int) int {
+	switch time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == daysIn(year, month) {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -0,0 +1,60 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// 2023-05-10 is a Wednesday
+	from := time.Date(2023, time.May, 10, 10, 30, 15, 0, time.UTC)
+	tests := []struct {
+		expression string
+		want       time.Time
+	}{
+		{expression: "* * * * *", want: time.Date(2023, time.May, 10, 10, 31, 0, 0, time.UTC)},
+		{expression: "*/15 * * * *", want: time.Date(2023, time.May, 10, 10, 45, 0, 0, time.UTC)},
+		{expression: "0 0 * * *", want: time.Date(2023, time.May, 11, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 9 * * MON", want: time.Date(2023, time.May, 15, 9, 0, 0, 0, time.UTC)},
+		{expression: "0 0 1 * *", want: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 L * *", want: time.Date(2023, time.May, 31, 0, 0, 0, 0, time.UTC)},
+		// June 2023 ends on a Friday
+		{expression: "0 0 LW 6 *", want: time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC)},
+		// 2023-07-01 is a Saturday, the nearest weekday in the month is Monday the 3rd
+		{expression: "0 0 1W 7 *", want: time.Date(2023, time.July, 3, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 * * 6L", want: time.Date(2023, time.May, 26, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 * * 2#3", want: time.Date(2023, time.May, 15, 0, 0, 0, 0, time.UTC)},
+		// day of month and day of week both restricted fire on either
+		{expression: "0 0 20 * MON", want: time.Date(2023, time.May, 15, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 29 2 *", want: time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{expression: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			schedule, err := Parse(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,11 +16,27 @@
 
 package models
 
-type UploadResponse struct {
+import (
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+)
+
+type Envelope interface {
+	Envelope() *Response
+}
+
+type Response struct {
 	Success  bool            `json:"success"`
 	Errors   []ResponseError `json:"errors"`
 	Messages []ResponseError `json:"messages"`
-	Result   ResponseResult  `json:"result"`
+}
+
+func (r *Response) Envelope() *Response {
+	return r
+}
+
+type UploadResponse struct {
+	Response
+	Result ResponseResult `json:"result"`
 }
 
 type ResponseResult struct {
@@ -31,9 +47,210 @@ type ResponseResult struct {
 	UsageModel           string   `json:"usage_model"`
 	Handlers             []string `json:"handlers"`
 	AvailableOnSubdomain bool     `json:"available_on_subdomain"`
+	Script               string   `json:"script,omitempty"`
 }
 
 type ResponseError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+type SettingsResponse struct {
+	Response
+	Result ScriptSettings `json:"result"`
+}
+
+type ScriptSettings struct {
+	UsageModel         string            `json:"usage_model"`
+	CompatibilityDate  string            `json:"compatibility_date"`
+	CompatibilityFlags []string          `json:"compatibility_flags"`
+	Logpush            bool              `json:"logpush"`
+	Placement          Placement         `json:"placement"`
+	Limits             Limits            `json:"limits"`
+	Bindings           []bindings.Worker `json:"bindings"`
+}
+
+type Placement struct {
+	Mode string `json:"mode"`
+}
+
+type Limits struct {
+	CPUMs int `json:"cpu_ms"`
+}
+
+type SchedulesResponse struct {
+	Response
+	Result SchedulesResult `json:"result"`
+}
+
+type SchedulesResult struct {
+	Schedules []Schedule `json:"schedules"`
+}
+
+type Schedule struct {
+	Cron       string `json:"cron"`
+	CreatedOn  string `json:"created_on,omitempty"`
+	ModifiedOn string `json:"modified_on,omitempty"`
+}
+
+type DeploymentsResponse struct {
+	Response
+	Result     DeploymentsResult `json:"result"`
+	ResultInfo *ResultInfo       `json:"result_info,omitempty"`
+}
+
+type DeploymentsResult struct {
+	Deployments []Deployment `json:"deployments"`
+}
+
+type Deployment struct {
+	ID          string              `json:"id"`
+	Source      string              `json:"source"`
+	Strategy    string              `json:"strategy"`
+	AuthorEmail string              `json:"author_email"`
+	CreatedOn   string              `json:"created_on"`
+	Versions    []DeploymentVersion `json:"versions"`
+	Annotations map[string]string   `json:"annotations,omitempty"`
+}
+
+type DeploymentVersion struct {
+	VersionID  string  `json:"version_id"`
+	Percentage float64 `json:"percentage"`
+}
+
+type DeploymentResponse struct {
+	Response
+	Result Deployment `json:"result"`
+}
+
+type DeploymentRequest struct {
+	Strategy string              `json:"strategy"`
+	Versions []DeploymentVersion `json:"versions"`
+}
+
+type VersionResponse struct {
+	Response
+	Result Version `json:"result"`
+}
+
+type Version struct {
+	ID          string            `json:"id"`
+	Number      int               `json:"number"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type TailResponse struct {
+	Response
+	Result Tail `json:"result"`
+}
+
+type Tail struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type AccountSubdomainResponse struct {
+	Response
+	Result AccountSubdomain `json:"result"`
+}
+
+type AccountSubdomain struct {
+	Subdomain string `json:"subdomain"`
+}
+
+type ScriptSubdomainResponse struct {
+	Response
+	Result ScriptSubdomain `json:"result"`
+}
+
+type ScriptSubdomain struct {
+	Enabled bool `json:"enabled"`
+}
+
+type SecretResponse struct {
+	Response
+	Result Secret `json:"result"`
+}
+
+type SecretsResponse struct {
+	Response
+	Result []Secret `json:"result"`
+}
+
+type Secret struct {
+	Name string `json:"name"`
+	Text string `json:"text,omitempty"`
+	Type string `json:"type"`
+}
+
+type RoutesResponse struct {
+	Response
+	Result []Route `json:"result"`
+}
+
+type RouteResponse struct {
+	Response
+	Result Route `json:"result"`
+}
+
+type DomainsResponse struct {
+	Response
+	Result []Domain `json:"result"`
+}
+
+type DomainResponse struct {
+	Response
+	Result Domain `json:"result"`
+}
+
+type Domain struct {
+	ID          string `json:"id,omitempty"`
+	ZoneID      string `json:"zone_id"`
+	ZoneName    string `json:"zone_name,omitempty"`
+	Hostname    string `json:"hostname"`
+	Service     string `json:"service"`
+	Environment string `json:"environment"`
+}
+
+type Route struct {
+	ID      string `json:"id,omitempty"`
+	Pattern string `json:"pattern"`
+	Script  string `json:"script,omitempty"`
+}
+
+type ScriptsResponse struct {
+	Response
+	Result     []Script    `json:"result"`
+	ResultInfo *ResultInfo `json:"result_info,omitempty"`
+}
+
+type ResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+	TotalPages int `json:"total_pages"`
+}
+
+type Script struct {
+	ID         string   `json:"id"`
+	Etag       string   `json:"etag"`
+	Handlers   []string `json:"handlers"`
+	CreatedOn  string   `json:"created_on"`
+	ModifiedOn string   `json:"modified_on"`
+	UsageModel string   `json:"usage_model"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+type ZoneSettingResponse struct {
+	Response
+	Result ZoneSetting `json:"result"`
+}
+
+type ZoneSetting struct {
+	ID         string `json:"id,omitempty"`
+	Value      string `json:"value"`
+	Editable   bool   `json:"editable,omitempty"`
+	ModifiedOn string `json:"modified_on,omitempty"`
+}
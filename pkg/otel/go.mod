@@ -0,0 +1,19 @@
+module github.com/loopholelabs/cloudflare/pkg/otel
+
+go 1.19
+
+require (
+	github.com/loopholelabs/cloudflare v0.1.0
+	go.opentelemetry.io/otel v1.16.0
+	go.opentelemetry.io/otel/metric v1.16.0
+)
+
+require (
+	github.com/coder/websocket v1.8.13 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	github.com/rs/zerolog v1.29.1 // indirect
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+)
+
+replace github.com/loopholelabs/cloudflare => ../../
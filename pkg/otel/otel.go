@@ -0,0 +1,113 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package otel records the Cloudflare client's request telemetry as OpenTelemetry
+// metrics. It is a separate module so that the client does not depend on the
+// OpenTelemetry API.
+//
+// The following instruments are created on the configured meter:
+//
+//	cloudflare.requests          counter    the number of requests
+//	cloudflare.request.retries   counter    the number of retried attempts
+//	cloudflare.request.duration  histogram  seconds until the response headers arrived
+//
+// Every measurement has the cloudflare.operation attribute, the client method that
+// made the request (for example "Upload"), and http.method. The http.status_code
+// attribute is added when a response was received, and error is set to true when
+// the request failed or returned a status of 400 or above. Only metrics are
+// recorded, no spans are created.
+package otel
+
+import (
+	"context"
+	"github.com/loopholelabs/cloudflare"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	instrumentationName = "github.com/loopholelabs/cloudflare"
+)
+
+type Metrics struct {
+	requests metric.Int64Counter
+	retries  metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+func New(provider metric.MeterProvider) (*Metrics, error) {
+	meter := provider.Meter(instrumentationName)
+
+	requests, err := meter.Int64Counter("cloudflare.requests", metric.WithDescription("The number of requests made to the cloudflare api"))
+	if err != nil {
+		return nil, err
+	}
+	retries, err := meter.Int64Counter("cloudflare.request.retries", metric.WithDescription("The number of retried attempts of requests made to the cloudflare api"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("cloudflare.request.duration", metric.WithUnit("s"), metric.WithDescription("The time taken to receive a response from the cloudflare api"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		requests: requests,
+		retries:  retries,
+		duration: duration,
+	}, nil
+}
+
+// Register creates Metrics from provider and installs them as options.ObserveFunc,
+// calling any previously configured ObserveFunc as well
+func Register(provider metric.MeterProvider, options *cloudflare.Options) (*Metrics, error) {
+	m, err := New(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := options.ObserveFunc
+	options.ObserveFunc = func(observation cloudflare.Observation) {
+		m.Observe(observation)
+		if previous != nil {
+			previous(observation)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) Observe(observation cloudflare.Observation) {
+	attributes := []attribute.KeyValue{
+		attribute.String("cloudflare.operation", observation.Name),
+		attribute.String("http.method", observation.Method),
+	}
+	if observation.Err == nil {
+		attributes = append(attributes, attribute.Int("http.status_code", observation.StatusCode))
+	}
+	if observation.Err != nil || observation.StatusCode >= 400 {
+		attributes = append(attributes, attribute.Bool("error", true))
+	}
+	options := metric.WithAttributes(attributes...)
+
+	// ObserveFunc is called without the request's context
+	ctx := context.Background()
+	m.requests.Add(ctx, 1, options)
+	if observation.Attempts > 1 {
+		m.retries.Add(ctx, int64(observation.Attempts-1), options)
+	}
+	m.duration.Record(ctx, observation.Duration.Seconds(), options)
+}
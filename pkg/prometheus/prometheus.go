@@ -0,0 +1,109 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package prometheus exposes the Cloudflare client's request telemetry as Prometheus
+// metrics. It is a separate module so that the client does not depend on the
+// Prometheus client library.
+//
+// The following metrics are registered, prefixed with the configured namespace:
+//
+//	cloudflare_requests_total{operation,status}                  counter
+//	cloudflare_request_errors_total{operation,status}            counter
+//	cloudflare_request_duration_seconds{operation,status}        histogram
+//
+// The operation label is the client method that made the request (for example
+// "Upload"), which keeps its cardinality fixed, and status is the HTTP status code,
+// or "error" if no response was received. Errors count both transport failures and
+// responses with a status of 400 or above.
+package prometheus
+
+import (
+	"github.com/loopholelabs/cloudflare"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+)
+
+type Metrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func New(registerer prometheus.Registerer, namespace string) (*Metrics, error) {
+	labels := []string{"operation", "status"}
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cloudflare",
+			Name:      "requests_total",
+			Help:      "The number of requests made to the cloudflare api",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cloudflare",
+			Name:      "request_errors_total",
+			Help:      "The number of requests to the cloudflare api that failed or returned an error status",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cloudflare",
+			Name:      "request_duration_seconds",
+			Help:      "The time taken to receive a response from the cloudflare api",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.errors, m.duration} {
+		err := registerer.Register(collector)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Register creates Metrics on registerer and installs them as options.ObserveFunc,
+// calling any previously configured ObserveFunc as well
+func Register(registerer prometheus.Registerer, namespace string, options *cloudflare.Options) (*Metrics, error) {
+	m, err := New(registerer, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := options.ObserveFunc
+	options.ObserveFunc = func(observation cloudflare.Observation) {
+		m.Observe(observation)
+		if previous != nil {
+			previous(observation)
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) Observe(observation cloudflare.Observation) {
+	status := "error"
+	if observation.Err == nil {
+		status = strconv.Itoa(observation.StatusCode)
+	}
+
+	m.requests.WithLabelValues(observation.Name, status).Inc()
+	m.duration.WithLabelValues(observation.Name, status).Observe(observation.Duration.Seconds())
+	if observation.Err != nil || observation.StatusCode >= 400 {
+		m.errors.WithLabelValues(observation.Name, status).Inc()
+	}
+}
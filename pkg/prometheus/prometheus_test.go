@@ -0,0 +1,101 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"errors"
+	"github.com/loopholelabs/cloudflare"
+	"github.com/prometheus/client_golang/prometheus"
+	"testing"
+	"time"
+)
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name        string
+		observation cloudflare.Observation
+		wantStatus  string
+		wantErrors  float64
+	}{
+		{
+			name:        "success",
+			observation: cloudflare.Observation{Name: "PutSecret", Operation: "updating worker secret", Method: "PUT", StatusCode: 200, Duration: time.Second},
+			wantStatus:  "200",
+		},
+		{
+			name:        "error status",
+			observation: cloudflare.Observation{Name: "PutSecret", Operation: "updating worker secret", Method: "PUT", StatusCode: 403, Duration: time.Second},
+			wantStatus:  "403",
+			wantErrors:  1,
+		},
+		{
+			name:        "transport error",
+			observation: cloudflare.Observation{Name: "PutSecret", Operation: "updating worker secret", Method: "PUT", Err: errors.New("connection reset"), Duration: time.Second},
+			wantStatus:  "error",
+			wantErrors:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			options := &cloudflare.Options{}
+			var previous int
+			options.ObserveFunc = func(cloudflare.Observation) { previous++ }
+			_, err := Register(registry, "test", options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options.ObserveFunc(tt.observation)
+			if previous != 1 {
+				t.Errorf("the previous ObserveFunc was called %d times", previous)
+			}
+
+			families, err := registry.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := make(map[string]float64)
+			for _, family := range families {
+				for _, metric := range family.GetMetric() {
+					labels := make(map[string]string)
+					for _, label := range metric.GetLabel() {
+						labels[label.GetName()] = label.GetValue()
+					}
+					if labels["operation"] != "PutSecret" || labels["status"] != tt.wantStatus {
+						t.Errorf("%s has labels %v, want operation PutSecret and status %s", family.GetName(), labels, tt.wantStatus)
+					}
+					switch {
+					case metric.GetCounter() != nil:
+						values[family.GetName()] = metric.GetCounter().GetValue()
+					case metric.GetHistogram() != nil:
+						values[family.GetName()] = float64(metric.GetHistogram().GetSampleCount())
+					}
+				}
+			}
+			if values["test_cloudflare_requests_total"] != 1 {
+				t.Errorf("requests_total = %v, want 1", values["test_cloudflare_requests_total"])
+			}
+			if values["test_cloudflare_request_duration_seconds"] != 1 {
+				t.Errorf("request_duration_seconds count = %v, want 1", values["test_cloudflare_request_duration_seconds"])
+			}
+			if values["test_cloudflare_request_errors_total"] != tt.wantErrors {
+				t.Errorf("request_errors_total = %v, want %v", values["test_cloudflare_request_errors_total"], tt.wantErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package wrangler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/evanw/esbuild/pkg/api"
+	"github.com/loopholelabs/cloudflare"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNameRequired     = errors.New("wrangler config is missing name")
+	ErrMainRequired     = errors.New("wrangler config is missing main")
+	ErrInvalidField     = errors.New("invalid wrangler config field")
+	ErrUnsupportedField = errors.New("unsupported wrangler config field")
+	ErrBundleRequired   = errors.New("main must be bundled")
+	ErrBundleFailed     = errors.New("error bundling main")
+)
+
+const (
+	ConfigFileName = "wrangler.toml"
+)
+
+// config is the subset of wrangler.toml that LoadFromDir supports. Any other key,
+// such as r2_buckets, d1_databases, services, durable_objects, routes, triggers or
+// env, is rejected with ErrUnsupportedField rather than silently dropped.
+type config struct {
+	Name               string            `toml:"name"`
+	Main               string            `toml:"main"`
+	NoBundle           bool              `toml:"no_bundle"`
+	CompatibilityDate  string            `toml:"compatibility_date"`
+	CompatibilityFlags []string          `toml:"compatibility_flags"`
+	Vars               map[string]string `toml:"vars"`
+	KVNamespaces       []kvNamespace     `toml:"kv_namespaces"`
+	WasmModules        map[string]string `toml:"wasm_modules"`
+	TextBlobs          map[string]string `toml:"text_blobs"`
+	DataBlobs          map[string]string `toml:"data_blobs"`
+}
+
+type kvNamespace struct {
+	Binding string `toml:"binding"`
+	ID      string `toml:"id"`
+}
+
+// LoadFromDir builds the upload input for the wrangler project in path. Unless
+// no_bundle is set, main is bundled with esbuild the way wrangler does, so it may
+// be TypeScript and import other files, and it is uploaded as a main module if it
+// has a default export.
+func LoadFromDir(path string) (*cloudflare.UploadInput, error) {
+	data, err := os.ReadFile(filepath.Join(path, ConfigFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", ConfigFileName, err)
+	}
+
+	var c config
+	metadata, err := toml.Decode(string(data), &c)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", ConfigFileName, err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedField, strings.Join(unsupportedKeys(undecoded), ", "))
+	}
+
+	if c.Name == "" {
+		return nil, ErrNameRequired
+	}
+	if c.Main == "" {
+		return nil, ErrMainRequired
+	}
+
+	script, mainModule, err := buildMain(path, c.Main, c.NoBundle)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &cloudflare.UploadInput{
+		Identifier:         c.Name,
+		WrapperScript:      script,
+		MainModule:         mainModule,
+		CompatibilityDate:  c.CompatibilityDate,
+		CompatibilityFlags: c.CompatibilityFlags,
+	}
+
+	for _, key := range sortedKeys(c.Vars) {
+		input.Bindings = append(input.Bindings, bindings.Worker{
+			Type: "plain_text",
+			Name: key,
+			Text: c.Vars[key],
+		})
+	}
+
+	for i, kvNamespace := range c.KVNamespaces {
+		if kvNamespace.Binding == "" || kvNamespace.ID == "" {
+			return nil, fmt.Errorf("%w: kv_namespaces[%d] requires binding and id", ErrInvalidField, i)
+		}
+		input.Bindings = append(input.Bindings, bindings.Worker{
+			Type:        "kv_namespace",
+			Name:        kvNamespace.Binding,
+			NamespaceID: kvNamespace.ID,
+		})
+	}
+
+	for _, module := range []struct {
+		field       string
+		files       map[string]string
+		bindingType string
+		contentType string
+	}{
+		{field: "wasm_modules", files: c.WasmModules, bindingType: "wasm_module", contentType: "application/wasm"},
+		{field: "text_blobs", files: c.TextBlobs, bindingType: "text_blob", contentType: "text/plain; charset=utf-8"},
+		{field: "data_blobs", files: c.DataBlobs, bindingType: "data_blob", contentType: "application/octet-stream"},
+	} {
+		for _, key := range sortedKeys(module.files) {
+			filePath := module.files[key]
+			content, err := os.ReadFile(filepath.Join(path, filePath))
+			if err != nil {
+				return nil, fmt.Errorf("error reading %s.%s: %w", module.field, key, err)
+			}
+			extension := strings.TrimPrefix(filepath.Ext(filePath), ".")
+			if extension == "" {
+				extension = "bin"
+			}
+			input.Files = append(input.Files, bindings.File{
+				Content:     content,
+				Extension:   extension,
+				ContentType: module.contentType,
+				Binding:     key,
+				Type:        module.bindingType,
+			})
+		}
+	}
+
+	return input, nil
+}
+
+// buildMain returns the script to upload for main and whether it is a main module,
+// which is decided by esbuild's analysis of its exports rather than its text. With
+// noBundle the file is uploaded as-is, so it must already be JavaScript.
+func buildMain(path string, main string, noBundle bool) ([]byte, bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, false, err
+	}
+	entryPoint := filepath.Join(absPath, main)
+	if noBundle {
+		switch filepath.Ext(main) {
+		case ".js", ".mjs", ".cjs":
+		default:
+			return nil, false, fmt.Errorf("%w: %s cannot be uploaded with no_bundle", ErrBundleRequired, main)
+		}
+	}
+
+	options := api.BuildOptions{
+		EntryPoints:   []string{entryPoint},
+		AbsWorkingDir: absPath,
+		Outdir:        filepath.Join(absPath, "dist"),
+		Bundle:        !noBundle,
+		Format:        api.FormatESModule,
+		Platform:      api.PlatformNeutral,
+		Target:        api.ES2022,
+		Conditions:    []string{"workerd", "worker", "browser"},
+		MainFields:    []string{"browser", "module", "main"},
+		Metafile:      true,
+		Write:         false,
+		LogLevel:      api.LogLevelSilent,
+	}
+	if !noBundle {
+		options.External = []string{"cloudflare:*", "node:*"}
+	}
+	result := api.Build(options)
+	if len(result.Errors) > 0 {
+		message := result.Errors[0]
+		if message.Location != nil {
+			return nil, false, fmt.Errorf("%w: %s:%d: %s", ErrBundleFailed, message.Location.File, message.Location.Line, message.Text)
+		}
+		return nil, false, fmt.Errorf("%w: %s", ErrBundleFailed, message.Text)
+	}
+	if len(result.OutputFiles) != 1 {
+		return nil, false, fmt.Errorf("%w: expected a single output file, got %d", ErrBundleFailed, len(result.OutputFiles))
+	}
+
+	var metafile struct {
+		Outputs map[string]struct {
+			Exports []string `json:"exports"`
+		} `json:"outputs"`
+	}
+	err = json.Unmarshal([]byte(result.Metafile), &metafile)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: invalid metafile: %s", ErrBundleFailed, err)
+	}
+	var mainModule bool
+	for _, output := range metafile.Outputs {
+		for _, name := range output.Exports {
+			if name == "default" {
+				mainModule = true
+			}
+		}
+	}
+
+	if noBundle {
+		script, err := os.ReadFile(entryPoint)
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading main module %s: %w", main, err)
+		}
+		return script, mainModule, nil
+	}
+	return result.OutputFiles[0].Contents, mainModule, nil
+}
+
+// unsupportedKeys returns the sorted names of the undecoded keys, leaving out those
+// nested under another undecoded key
+func unsupportedKeys(keys []toml.Key) []string {
+	all := make([]string, 0, len(keys))
+	for _, key := range keys {
+		all = append(all, key.String())
+	}
+	sort.Strings(all)
+	var names []string
+next:
+	for _, name := range all {
+		for _, parent := range names {
+			if strings.HasPrefix(name, parent+".") {
+				continue next
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func sortedKeys(table map[string]string) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,226 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package wrangler
+
+import (
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadFromDir(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          map[string]string
+		wantErr        error
+		wantErrText    string
+		wantMainModule bool
+		wantScript     func(t *testing.T, script string)
+		wantBindings   []bindings.Worker
+		wantFiles      int
+	}{
+		{
+			name: "typescript module is bundled",
+			files: map[string]string{
+				"wrangler.toml":   "name = \"worker\"\nmain = \"src/index.ts\"\ncompatibility_date = \"2023-05-01\"\n",
+				"src/index.ts":    "import { greeting } from \"./greeting\"\nexport default { fetch(): Response { return new Response(greeting) } }\n",
+				"src/greeting.ts": "export const greeting: string = \"hello from greeting\"\n",
+			},
+			wantMainModule: true,
+			wantScript: func(t *testing.T, script string) {
+				if !strings.Contains(script, "hello from greeting") {
+					t.Errorf("expected the import to be bundled, got %q", script)
+				}
+				if strings.Contains(script, ": string") || strings.Contains(script, "from \"./greeting\"") {
+					t.Errorf("expected bundled javascript, got %q", script)
+				}
+			},
+		},
+		{
+			name: "service worker is not a main module",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\n",
+				"index.js":      "// replaces the old export default handler\naddEventListener(\"fetch\", (event) => event.respondWith(new Response(\"ok\")))\n",
+			},
+		},
+		{
+			name: "no_bundle uploads main as-is",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.mjs\"\nno_bundle = true\n",
+				"index.mjs":     "export default {\n  fetch() { return new Response(\"ok\") }\n}\n",
+			},
+			wantMainModule: true,
+			wantScript: func(t *testing.T, script string) {
+				if script != "export default {\n  fetch() { return new Response(\"ok\") }\n}\n" {
+					t.Errorf("expected the unmodified script, got %q", script)
+				}
+			},
+		},
+		{
+			name: "no_bundle rejects typescript",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.ts\"\nno_bundle = true\n",
+				"index.ts":      "export default {}\n",
+			},
+			wantErr: ErrBundleRequired,
+		},
+		{
+			name: "bundling errors are returned",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\n",
+				"index.js":      "import \"./missing\"\n",
+			},
+			wantErr: ErrBundleFailed,
+		},
+		{
+			name: "bindings and files",
+			files: map[string]string{
+				"wrangler.toml": `name = "worker"
+main = "index.js"
+compatibility_flags = ["nodejs_compat"]
+
+[vars]
+B = """
+multi
+line"""
+A = 'literal'
+
+[[kv_namespaces]]
+binding = "CACHE"
+id = "namespace"
+
+[text_blobs]
+TEXT = "data/text.txt"
+`,
+				"index.js":      "export default {}\n",
+				"data/text.txt": "text",
+			},
+			wantMainModule: true,
+			wantBindings: []bindings.Worker{
+				{Type: "plain_text", Name: "A", Text: "literal"},
+				{Type: "plain_text", Name: "B", Text: "multi\nline"},
+				{Type: "kv_namespace", Name: "CACHE", NamespaceID: "namespace"},
+			},
+			wantFiles: 1,
+		},
+		{
+			name: "missing name",
+			files: map[string]string{
+				"wrangler.toml": "main = \"index.js\"\n",
+			},
+			wantErr: ErrNameRequired,
+		},
+		{
+			name: "missing main",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\n",
+			},
+			wantErr: ErrMainRequired,
+		},
+		{
+			name: "incomplete kv namespace",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\n[[kv_namespaces]]\nbinding = \"CACHE\"\n",
+				"index.js":      "export default {}\n",
+			},
+			wantErr: ErrInvalidField,
+		},
+		{
+			name: "unsupported bindings",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\n[[r2_buckets]]\nbinding = \"BUCKET\"\nbucket_name = \"bucket\"\n[[d1_databases]]\nbinding = \"DB\"\n[durable_objects]\nbindings = []\n",
+			},
+			wantErr:     ErrUnsupportedField,
+			wantErrText: "d1_databases, durable_objects, r2_buckets",
+		},
+		{
+			name: "unsupported routes, triggers and environments",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\nroutes = [\"example.com/*\"]\n[triggers]\ncrons = []\n[env.staging]\nname = \"worker-staging\"\n",
+			},
+			wantErr:     ErrUnsupportedField,
+			wantErrText: "env.staging, routes, triggers",
+		},
+		{
+			name: "unsupported nested key",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\"\nmain = \"index.js\"\n[[kv_namespaces]]\nbinding = \"CACHE\"\nid = \"namespace\"\npreview_id = \"preview\"\n",
+			},
+			wantErr:     ErrUnsupportedField,
+			wantErrText: "kv_namespaces.preview_id",
+		},
+		{
+			name: "invalid toml",
+			files: map[string]string{
+				"wrangler.toml": "name = \"worker\nmain = \"index.js\"\n",
+			},
+			wantErrText: "error parsing wrangler.toml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			input, err := LoadFromDir(dir)
+			if test.wantErr != nil || test.wantErrText != "" {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+					t.Errorf("expected %v, got %v", test.wantErr, err)
+				}
+				if !strings.Contains(err.Error(), test.wantErrText) {
+					t.Errorf("expected the error to contain %q, got %q", test.wantErrText, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if input.Identifier != "worker" {
+				t.Errorf("expected identifier worker, got %q", input.Identifier)
+			}
+			if input.MainModule != test.wantMainModule {
+				t.Errorf("expected MainModule %t, got %t", test.wantMainModule, input.MainModule)
+			}
+			if test.wantScript != nil {
+				test.wantScript(t, string(input.WrapperScript))
+			}
+			if !reflect.DeepEqual(input.Bindings, test.wantBindings) {
+				t.Errorf("expected bindings %+v, got %+v", test.wantBindings, input.Bindings)
+			}
+			if len(input.Files) != test.wantFiles {
+				t.Errorf("expected %d files, got %d", test.wantFiles, len(input.Files))
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+)
+
+// Priority is the importance of a call, for gateways in front of the API that
+// shed load by priority. It is only sent if Options.PriorityHeader is set.
+type Priority int
+
+const (
+	PriorityUnset Priority = iota
+	PriorityLow
+	PriorityNormal
+	PriorityHigh
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return ""
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority sets the priority of every request made with ctx, so that for
+// example bulk reconciliation deploys can be marked PriorityLow and hotfix
+// deploys PriorityHigh
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+func (c *Cloudflare) setPriority(req *http.Request) {
+	if c.options.PriorityHeader == "" {
+		return
+	}
+	priority, _ := req.Context().Value(priorityKey{}).(Priority)
+	if priority == PriorityUnset {
+		return
+	}
+
+	value, ok := c.options.PriorityValues[priority]
+	if !ok {
+		value = priority.String()
+	}
+	if value != "" {
+		req.Header.Set(c.options.PriorityHeader, value)
+	}
+}
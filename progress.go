@@ -0,0 +1,95 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+type progressKey struct{}
+
+// ProgressFunc is called as an upload's request body is sent, with the number of
+// bytes sent so far and the total size of the body
+type ProgressFunc func(sent int64, total int64)
+
+func withProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+type progressReader struct {
+	io.ReadCloser
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.fn(r.sent, r.total)
+	}
+	return n, err
+}
+
+// withProgressReader reports the progress of reading body to fn, starting from
+// zero, or returns body unchanged if fn is nil
+func withProgressReader(body io.ReadCloser, total int64, fn ProgressFunc) io.ReadCloser {
+	if fn == nil {
+		return body
+	}
+	fn(0, total)
+	return &progressReader{ReadCloser: body, total: total, fn: fn}
+}
+
+// progressAggregate combines the progress of several concurrent uploads into a
+// single cumulative figure. The total grows as each upload starts, since the size
+// of an upload is only known once its body has been assembled.
+type progressAggregate struct {
+	mu    sync.Mutex
+	sent  int64
+	total int64
+	fn    ProgressFunc
+}
+
+// track returns a ProgressFunc for a single upload that feeds the aggregate, and
+// also calls next with the upload's own progress if it is non-nil
+func (a *progressAggregate) track(next ProgressFunc) ProgressFunc {
+	started := false
+	var last int64
+	return func(sent int64, total int64) {
+		a.mu.Lock()
+		if !started {
+			a.total += total
+			started = true
+		}
+		a.sent += sent - last
+		last = sent
+		a.fn(a.sent, a.total)
+		a.mu.Unlock()
+		if next != nil {
+			next(sent, total)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitStatus is the rate limit budget reported by the Cloudflare API on a response.
+//
+// The API reports its budget using the draft IETF headers, for example
+// `Ratelimit: "default";r=1199;t=5` and `Ratelimit-Policy: "default";q=1200;w=300`.
+// Some endpoints instead send X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset, which are used as a fallback. Retry-After is read on 429s.
+// Fields that were not present on the response are left as zero.
+type RateLimitStatus struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	Window     time.Duration
+	RetryAfter time.Duration
+	ObservedAt time.Time
+}
+
+// RateLimit returns the last rate limit status observed on any response, and
+// false if no response has carried rate limit headers yet
+func (c *Cloudflare) RateLimit() (RateLimitStatus, bool) {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+	if c.rateLimit == nil {
+		return RateLimitStatus{}, false
+	}
+	return *c.rateLimit, true
+}
+
+func (c *Cloudflare) observeRateLimit(resp *http.Response) {
+	status, ok := parseRateLimit(resp.Header)
+	if !ok {
+		return
+	}
+	status.ObservedAt = time.Now()
+
+	c.rateLimitMu.Lock()
+	c.rateLimit = &status
+	c.rateLimitMu.Unlock()
+
+	if c.options.ObserveRateLimit != nil {
+		c.options.ObserveRateLimit(status)
+	}
+}
+
+func parseRateLimit(header http.Header) (RateLimitStatus, bool) {
+	var status RateLimitStatus
+	found := false
+
+	if v := header.Get("Ratelimit"); v != "" {
+		params := rateLimitParams(v)
+		if r, ok := params["r"]; ok {
+			status.Remaining = r
+			found = true
+		}
+		if t, ok := params["t"]; ok {
+			status.Reset = time.Duration(t) * time.Second
+			found = true
+		}
+	} else {
+		if r, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+			status.Remaining = r
+			found = true
+		}
+		if t, err := strconv.Atoi(header.Get("X-RateLimit-Reset")); err == nil {
+			status.Reset = time.Duration(t) * time.Second
+			found = true
+		}
+	}
+
+	if v := header.Get("Ratelimit-Policy"); v != "" {
+		params := rateLimitParams(v)
+		if q, ok := params["q"]; ok {
+			status.Limit = q
+			found = true
+		}
+		if w, ok := params["w"]; ok {
+			status.Window = time.Duration(w) * time.Second
+		}
+	} else if l, err := strconv.Atoi(header.Get("X-RateLimit-Limit")); err == nil {
+		status.Limit = l
+		found = true
+	}
+
+	if s, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		status.RetryAfter = time.Duration(s) * time.Second
+		found = true
+	}
+
+	return status, found
+}
+
+// rateLimitParams parses the integer parameters of the first item in a structured
+// rate limit header such as `"default";r=50;t=30`
+func rateLimitParams(value string) map[string]int {
+	params := make(map[string]int)
+	item := strings.SplitN(value, ",", 2)[0]
+	for _, param := range strings.Split(item, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			continue
+		}
+		params[strings.ToLower(key)] = n
+	}
+	return params
+}
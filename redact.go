@@ -0,0 +1,60 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"strings"
+)
+
+// redactedError replaces the message of an error for logging while keeping the
+// original error available for errors.Is and errors.As
+type redactedError struct {
+	err     error
+	message string
+}
+
+func (e *redactedError) Error() string {
+	return e.message
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+func maskAccountID(accountID string) string {
+	if len(accountID) <= 4 {
+		return strings.Repeat("*", len(accountID))
+	}
+	return strings.Repeat("*", len(accountID)-4) + accountID[len(accountID)-4:]
+}
+
+// redact masks the account id in s when RedactAccountID is set
+func (c *Cloudflare) redact(s string) string {
+	if !c.options.RedactAccountID || c.options.UserID == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, c.options.UserID, maskAccountID(c.options.UserID))
+}
+
+// redactErr masks the account id in the message of err when RedactAccountID is set,
+// and should be used for every error passed to the logger
+func (c *Cloudflare) redactErr(err error) error {
+	if err == nil || !c.options.RedactAccountID {
+		return err
+	}
+	return &redactedError{err: err, message: c.redact(err.Error())}
+}
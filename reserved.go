@@ -0,0 +1,61 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReservedNames returns a matcher for Options.IsReservedName that matches each
+// name exactly, or as a prefix if it ends in "*"
+func ReservedNames(names ...string) func(scriptName string) bool {
+	exact := make(map[string]struct{}, len(names))
+	var prefixes []string
+	for _, name := range names {
+		if strings.HasSuffix(name, "*") {
+			prefixes = append(prefixes, strings.TrimSuffix(name, "*"))
+		} else {
+			exact[name] = struct{}{}
+		}
+	}
+
+	return func(scriptName string) bool {
+		if _, ok := exact[scriptName]; ok {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(scriptName, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// checkReservedName rejects uploads whose script name, including the client's
+// Prefix, is reserved by Options.IsReservedName
+func (c *Cloudflare) checkReservedName(identifier string) error {
+	if c.options.IsReservedName == nil {
+		return nil
+	}
+	scriptName := c.options.Prefix + identifier
+	if c.options.IsReservedName(scriptName) {
+		return fmt.Errorf("%w: %s", ErrReservedName, scriptName)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultBaseBackoff = time.Millisecond * 500
+	DefaultMaxBackoff  = time.Second * 30
+)
+
+// Sleeper waits between retries. It is an interface so tests can substitute a fake
+// clock and assert the exact backoff sequence without sleeping.
+type Sleeper interface {
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+type timeSleeper struct{}
+
+func (timeSleeper) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// AuthTransport is an http.RoundTripper for the Cloudflare API that sets the
+// Authorization header from Token, unless the request already has one, and retries
+// failed requests up to MaxRetries times with exponential backoff, honoring
+// Retry-After. Requests with a body are only retried if GetBody is set, which
+// http.NewRequest does for in-memory bodies.
+//
+// If Limiter is set, every attempt waits for the limiter's rate.
+//
+// Unless DisableCompression is set, requests without an Accept-Encoding header ask
+// for gzip or deflate and the response is decoded here, whatever Base is.
+type AuthTransport struct {
+	Base               http.RoundTripper
+	Token              string
+	MaxRetries         int
+	BaseBackoff        time.Duration
+	MaxBackoff         time.Duration
+	Sleeper            Sleeper
+	Limiter            *Limiter
+	DisableCompression bool
+}
+
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	sleeper := t.Sleeper
+	if sleeper == nil {
+		sleeper = timeSleeper{}
+	}
+	decode := !t.DisableCompression && req.Header.Get("Accept-Encoding") == ""
+	// a RoundTripper must close the request body even when it fails, Base only
+	// does so once it has been called
+	closeBody := func() {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if t.Limiter != nil {
+			if err := t.Limiter.Wait(req.Context()); err != nil {
+				closeBody()
+				return nil, err
+			}
+		}
+		attemptReq := req.Clone(req.Context())
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				closeBody()
+				return nil, err
+			}
+			attemptReq.Body = body
+			if transformed, ok := body.(*transformedBody); ok {
+				transformed.applyHeader(attemptReq)
+			}
+		}
+		if attemptReq.Header.Get("Authorization") == "" && t.Token != "" {
+			attemptReq.Header.Set("Authorization", "Bearer "+t.Token)
+		}
+		if decode {
+			attemptReq.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+
+		recordRequest(attemptReq)
+		resp, err := base.RoundTrip(attemptReq)
+		if attempt >= t.MaxRetries || req.Context().Err() != nil || !retryable(req, resp, err) {
+			if decode && err == nil {
+				decodeResponseBody(resp)
+			}
+			return resp, err
+		}
+
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+				delay = time.Duration(seconds) * time.Second
+				if t.MaxBackoff > 0 && delay > t.MaxBackoff {
+					delay = t.MaxBackoff
+				}
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if sleepErr := sleeper.Sleep(req.Context(), delay); sleepErr != nil {
+			closeBody()
+			if err == nil {
+				err = sleepErr
+			}
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before retry number attempt (starting at 0), doubling
+// from BaseBackoff up to MaxBackoff
+func (t *AuthTransport) backoff(attempt int) time.Duration {
+	base, max := t.BaseBackoff, t.MaxBackoff
+	if base <= 0 {
+		base = DefaultBaseBackoff
+	}
+	if max <= 0 {
+		max = DefaultMaxBackoff
+	}
+	return exponentialBackoff(base, max, attempt)
+}
+
+// exponentialBackoff returns base doubled attempt times, capped at max
+func exponentialBackoff(base time.Duration, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// retryable reports whether a request can be retried. Rate limited requests were
+// not processed and are always retried, while server and transport errors are only
+// retried for idempotent methods since the request may have been applied.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	idempotent := req.Method == "GET" || req.Method == "PUT" || req.Method == "DELETE"
+	if err != nil {
+		return idempotent
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return idempotent && resp.StatusCode >= 500
+}
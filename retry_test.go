@@ -0,0 +1,470 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+type sleeperFunc func(ctx context.Context, d time.Duration) error
+
+func (f sleeperFunc) Sleep(ctx context.Context, d time.Duration) error {
+	return f(ctx, d)
+}
+
+func TestAuthTransportClosesBodyOnEarlyReturn(t *testing.T) {
+	errSleep := errors.New("sleep failed")
+	errGetBody := errors.New("get body failed")
+	unavailable := func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+
+	tests := []struct {
+		name      string
+		transport func() *AuthTransport
+		ctx       func() context.Context
+		getBody   func() (io.ReadCloser, error)
+		wantErr   error
+	}{
+		{
+			name: "limiter wait fails",
+			transport: func() *AuthTransport {
+				limiter := NewLimiter(0.001, 1, 0)
+				_ = limiter.Wait(context.Background())
+				return &AuthTransport{Base: roundTripperFunc(unavailable), Limiter: limiter}
+			},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "get body fails",
+			transport: func() *AuthTransport {
+				return &AuthTransport{Base: roundTripperFunc(unavailable), MaxRetries: 1, Sleeper: sleeperFunc(func(context.Context, time.Duration) error { return nil })}
+			},
+			getBody: func() (io.ReadCloser, error) { return nil, errGetBody },
+			wantErr: errGetBody,
+		},
+		{
+			name: "sleep fails",
+			transport: func() *AuthTransport {
+				return &AuthTransport{Base: roundTripperFunc(unavailable), MaxRetries: 1, Sleeper: sleeperFunc(func(context.Context, time.Duration) error { return errSleep })}
+			},
+			wantErr: errSleep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = tt.ctx()
+			}
+			body := &closeRecorder{Reader: strings.NewReader("body")}
+			req, err := http.NewRequestWithContext(ctx, "PUT", "http://api.example/", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.GetBody = tt.getBody
+			if req.GetBody == nil {
+				req.GetBody = func() (io.ReadCloser, error) {
+					return io.NopCloser(strings.NewReader("body")), nil
+				}
+			}
+
+			resp, err := tt.transport().RoundTrip(req)
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RoundTrip() error = %v, want %v", err, tt.wantErr)
+			}
+			if !body.closed {
+				t.Errorf("request body was not closed")
+			}
+		})
+	}
+}
+
+// statusSequence serves the given statuses in order, repeating the last one, and
+// sets Retry-After when the matching entry of retryAfter is not empty
+type statusSequence struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter []string
+	requests   int
+	bodies     []string
+	auth       []string
+}
+
+func (s *statusSequence) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	i := s.requests
+	if i >= len(s.statuses) {
+		i = len(s.statuses) - 1
+	}
+	s.requests++
+	s.bodies = append(s.bodies, string(body))
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	s.mu.Unlock()
+	if i < len(s.retryAfter) && s.retryAfter[i] != "" {
+		w.Header().Set("Retry-After", s.retryAfter[i])
+	}
+	w.WriteHeader(s.statuses[i])
+}
+
+func TestAuthTransportBackoff(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxRetries  int
+		baseBackoff time.Duration
+		maxBackoff  time.Duration
+		statuses    []int
+		retryAfter  []string
+		wantSleeps  []time.Duration
+		wantStatus  int
+	}{
+		{
+			name:        "no retries",
+			baseBackoff: time.Second,
+			statuses:    []int{http.StatusServiceUnavailable},
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:        "doubles until success",
+			maxRetries:  5,
+			baseBackoff: time.Second,
+			maxBackoff:  time.Minute,
+			statuses:    []int{500, 502, 503, 200},
+			wantSleeps:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "capped at max backoff",
+			maxRetries:  5,
+			baseBackoff: time.Second,
+			maxBackoff:  5 * time.Second,
+			statuses:    []int{503},
+			wantSleeps:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		{
+			name:       "defaults",
+			maxRetries: 3,
+			statuses:   []int{503},
+			wantSleeps: []time.Duration{DefaultBaseBackoff, 2 * DefaultBaseBackoff, 4 * DefaultBaseBackoff},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:        "retry after overrides backoff",
+			maxRetries:  2,
+			baseBackoff: time.Second,
+			maxBackoff:  time.Minute,
+			statuses:    []int{429, 429, 200},
+			retryAfter:  []string{"7", ""},
+			wantSleeps:  []time.Duration{7 * time.Second, 2 * time.Second},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "retry after capped at max backoff",
+			maxRetries:  1,
+			baseBackoff: time.Second,
+			maxBackoff:  10 * time.Second,
+			statuses:    []int{429, 200},
+			retryAfter:  []string{"120"},
+			wantSleeps:  []time.Duration{10 * time.Second},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "invalid retry after is ignored",
+			maxRetries:  1,
+			baseBackoff: time.Second,
+			maxBackoff:  time.Minute,
+			statuses:    []int{429, 200},
+			retryAfter:  []string{"soon"},
+			wantSleeps:  []time.Duration{time.Second},
+			wantStatus:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(&statusSequence{statuses: tt.statuses, retryAfter: tt.retryAfter})
+			t.Cleanup(server.Close)
+
+			var sleeps []time.Duration
+			transport := &AuthTransport{
+				MaxRetries:  tt.maxRetries,
+				BaseBackoff: tt.baseBackoff,
+				MaxBackoff:  tt.maxBackoff,
+				Sleeper: sleeperFunc(func(_ context.Context, d time.Duration) error {
+					sleeps = append(sleeps, d)
+					return nil
+				}),
+			}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(sleeps, tt.wantSleeps) {
+				t.Errorf("sleeps = %v, want %v", sleeps, tt.wantSleeps)
+			}
+		})
+	}
+}
+
+func TestClientBackoffOptions(t *testing.T) {
+	var requests int
+	var sleeps []time.Duration
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"success":true,"result":{"schedules":[]}}`))
+	}), func(options *Options) {
+		options.MaxRetries = 3
+		options.BaseBackoff = 3 * time.Second
+		options.MaxBackoff = 4 * time.Second
+		options.Sleeper = sleeperFunc(func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		})
+	})
+
+	_, err := c.GetCronTriggers(context.Background(), "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{3 * time.Second, 4 * time.Second}; !reflect.DeepEqual(sleeps, want) {
+		t.Errorf("sleeps = %v, want %v", sleeps, want)
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		noGetBody    bool
+		auth         string
+		statuses     []int
+		wantRequests int
+		wantStatus   int
+		wantAuth     string
+	}{
+		{
+			name:         "sets authorization",
+			method:       "GET",
+			statuses:     []int{200},
+			wantRequests: 1,
+			wantStatus:   200,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "keeps caller authorization",
+			method:       "GET",
+			auth:         "Bearer other",
+			statuses:     []int{200},
+			wantRequests: 1,
+			wantStatus:   200,
+			wantAuth:     "Bearer other",
+		},
+		{
+			name:         "retries idempotent server errors and resends the body",
+			method:       "PUT",
+			body:         "payload",
+			statuses:     []int{500, 503, 200},
+			wantRequests: 3,
+			wantStatus:   200,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "does not retry server errors for post",
+			method:       "POST",
+			body:         "payload",
+			statuses:     []int{500, 200},
+			wantRequests: 1,
+			wantStatus:   500,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "retries rate limited post",
+			method:       "POST",
+			body:         "payload",
+			statuses:     []int{429, 200},
+			wantRequests: 2,
+			wantStatus:   200,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "does not retry a body it cannot replay",
+			method:       "PUT",
+			body:         "payload",
+			noGetBody:    true,
+			statuses:     []int{503, 200},
+			wantRequests: 1,
+			wantStatus:   503,
+			wantAuth:     "Bearer token",
+		},
+		{
+			name:         "does not retry client errors",
+			method:       "GET",
+			statuses:     []int{404, 200},
+			wantRequests: 1,
+			wantStatus:   404,
+			wantAuth:     "Bearer token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequence := &statusSequence{statuses: tt.statuses}
+			server := httptest.NewServer(sequence)
+			t.Cleanup(server.Close)
+
+			transport := &AuthTransport{
+				Token:      "token",
+				MaxRetries: 3,
+				Sleeper:    sleeperFunc(func(context.Context, time.Duration) error { return nil }),
+			}
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, server.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if sequence.requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", sequence.requests, tt.wantRequests)
+			}
+			for i := range sequence.bodies {
+				if sequence.bodies[i] != tt.body {
+					t.Errorf("attempt %d body = %q, want %q", i, sequence.bodies[i], tt.body)
+				}
+				if sequence.auth[i] != tt.wantAuth {
+					t.Errorf("attempt %d Authorization = %q, want %q", i, sequence.auth[i], tt.wantAuth)
+				}
+			}
+			if req.Header.Get("Authorization") != tt.auth {
+				t.Errorf("the caller's request was modified")
+			}
+		})
+	}
+}
+
+func TestAuthTransportDecodesGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			_, _ = w.Write([]byte("plain"))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write([]byte("compressed"))
+		_ = writer.Close()
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name               string
+		disableCompression bool
+		want               string
+	}{
+		{name: "compressed", want: "compressed"},
+		{name: "compression disabled", disableCompression: true, want: "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := http.DefaultTransport.(*http.Transport).Clone()
+			base.DisableCompression = true
+			transport := &AuthTransport{Base: base, DisableCompression: tt.disableCompression}
+			req, err := http.NewRequest("GET", server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("body = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
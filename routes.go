@@ -0,0 +1,168 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/url"
+	"time"
+)
+
+// ListDomains lists the custom domains attached to the worker
+func (c *Cloudflare) ListDomains(ctx context.Context, identifier string) ([]models.Domain, error) {
+	query := url.Values{"service": {c.options.Prefix + identifier}}
+	res := new(models.DomainsResponse)
+	err := c.doJSON(ctx, "GET", c.accountURL()+"/workers/domains?"+query.Encode(), nil, res, "ListDomains", "listing worker domains")
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
+
+// AttachDomain attaches the worker to hostname as a custom domain in zoneID
+func (c *Cloudflare) AttachDomain(ctx context.Context, zoneID string, hostname string, identifier string) (*models.Domain, error) {
+	domain := &models.Domain{
+		ZoneID:      zoneID,
+		Hostname:    hostname,
+		Service:     c.options.Prefix + identifier,
+		Environment: "production",
+	}
+	res := new(models.DomainResponse)
+	err := c.doJSON(ctx, "PUT", c.accountURL()+"/workers/domains", domain, res, "AttachDomain", fmt.Sprintf("attaching worker domain %s", hostname))
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
+
+// DetachDomain removes the custom domain with the given id from its worker
+func (c *Cloudflare) DetachDomain(ctx context.Context, domainID string) error {
+	return c.doJSON(ctx, "DELETE", c.accountURL()+"/workers/domains/"+domainID, nil, new(models.Response), "DetachDomain", "detaching worker domain")
+}
+
+// WaitForDomain polls every interval until hostname is attached to the worker as a
+// custom domain, or ctx is done
+func (c *Cloudflare) WaitForDomain(ctx context.Context, identifier string, hostname string, interval time.Duration) error {
+	return WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		domains, err := c.ListDomains(ctx, identifier)
+		if err != nil {
+			return false, err
+		}
+		for _, domain := range domains {
+			if domain.Hostname == hostname {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, interval)
+}
+
+// checkRouted returns ErrNoRoutes unless the worker has a custom domain, or a
+// route in one of zoneIDs
+func (c *Cloudflare) checkRouted(ctx context.Context, identifier string, zoneIDs []string) error {
+	domains, err := c.ListDomains(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	if len(domains) > 0 {
+		return nil
+	}
+
+	for _, zoneID := range zoneIDs {
+		routes, err := c.ListRoutes(ctx, zoneID)
+		if err != nil {
+			return err
+		}
+		for _, route := range routes {
+			if route.Script == c.options.Prefix+identifier {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrNoRoutes, identifier)
+}
+
+func (c *Cloudflare) ListRoutes(ctx context.Context, zoneID string) ([]models.Route, error) {
+	res := new(models.RoutesResponse)
+	err := c.doJSON(ctx, "GET", c.zoneURL(zoneID)+"/workers/routes", nil, res, "ListRoutes", "listing worker routes")
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
+
+func (c *Cloudflare) CreateRoute(ctx context.Context, zoneID string, pattern string, identifier string) (*models.Route, error) {
+	route := &models.Route{
+		Pattern: pattern,
+		Script:  c.options.Prefix + identifier,
+	}
+	res := new(models.RouteResponse)
+	err := c.doJSON(ctx, "POST", c.zoneURL(zoneID)+"/workers/routes", route, res, "CreateRoute", fmt.Sprintf("creating worker route %s", pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
+
+func (c *Cloudflare) DeleteRoute(ctx context.Context, zoneID string, routeID string) error {
+	return c.doJSON(ctx, "DELETE", c.zoneURL(zoneID)+"/workers/routes/"+routeID, nil, new(models.RouteResponse), "DeleteRoute", fmt.Sprintf("deleting worker route %s", routeID))
+}
+
+// DeployToRoute uploads the worker without enabling its workers.dev subdomain and
+// then creates a route for it. If the route can't be created and the worker didn't
+// exist before, the upload is deleted again, while an existing worker keeps the
+// new version. It takes an *UploadInput rather than a single *bindings.Function so
+// that everything Upload accepts can be deployed to a route.
+func (c *Cloudflare) DeployToRoute(ctx context.Context, zoneID string, pattern string, input *UploadInput) (*bindings.UploadedFunction, error) {
+	newScript := false
+	_, err := c.GetFunction(ctx, input.Identifier)
+	if errors.Is(err, ErrFunctionNotFound) {
+		newScript = true
+	} else if err != nil {
+		return nil, err
+	}
+
+	routeInput := *input
+	routeInput.SkipSubdomain = true
+	uploaded, err := c.Upload(ctx, &routeInput)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.CreateRoute(ctx, zoneID, pattern, input.Identifier)
+	if err != nil {
+		if newScript {
+			rollbackCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+			defer cancel()
+			if rollbackErr := c.Delete(rollbackCtx, input.Identifier); rollbackErr != nil {
+				c.log(ctx).Error().Err(c.redactErr(rollbackErr)).Str("identifier", input.Identifier).Msg("error rolling back worker upload")
+			}
+		}
+		return nil, err
+	}
+
+	return uploaded, nil
+}
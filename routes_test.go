@@ -0,0 +1,122 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestDeployToRoute(t *testing.T) {
+	const (
+		scriptsPath = "/accounts/account/workers/scripts"
+		routesPath  = "/zones/zone/workers/routes"
+	)
+	tests := []struct {
+		name        string
+		scripts     string
+		routeFails  bool
+		wantErr     bool
+		wantDeletes int
+	}{
+		{
+			name:    "new worker",
+			scripts: `{"success":true,"result":[]}`,
+		},
+		{
+			name:        "route fails for a new worker",
+			scripts:     `{"success":true,"result":[]}`,
+			routeFails:  true,
+			wantErr:     true,
+			wantDeletes: 1,
+		},
+		{
+			name:       "route fails for an existing worker",
+			scripts:    `{"success":true,"result":[{"id":"worker"}]}`,
+			routeFails: true,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := map[string]string{
+				"GET " + scriptsPath:                tt.scripts,
+				"DELETE " + scriptsPath + "/worker": `{"success":true}`,
+			}
+			if !tt.routeFails {
+				responses["POST "+routesPath] = `{"success":true,"result":{"id":"route","pattern":"example.com/*","script":"worker"}}`
+			}
+			api := newFakeAPI(responses)
+			upload := new(uploadServer)
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == "PUT" && r.URL.Path == scriptsPath+"/worker" {
+					api.mu.Lock()
+					api.requests = append(api.requests, "PUT "+r.URL.Path)
+					api.mu.Unlock()
+					upload.ServeHTTP(w, r)
+					return
+				}
+				api.ServeHTTP(w, r)
+			}), nil)
+
+			_, err := c.DeployToRoute(context.Background(), "zone", "example.com/*", &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte("export default {}"),
+				MainModule:    true,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeployToRoute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n := api.count("PUT " + scriptsPath + "/worker"); n != 1 {
+				t.Errorf("uploads = %d, want 1", n)
+			}
+			if n := api.count("POST " + routesPath); n != 1 {
+				t.Errorf("route requests = %d, want 1", n)
+			}
+			if n := api.count("DELETE " + scriptsPath + "/worker"); n != tt.wantDeletes {
+				t.Errorf("deletes = %d, want %d", n, tt.wantDeletes)
+			}
+			for i, request := range api.requests {
+				if request == "POST "+routesPath && (i == 0 || api.requests[i-1] != "PUT "+scriptsPath+"/worker") {
+					t.Errorf("expected the route to be created after the upload, got %v", api.requests)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployToRouteUploadFails(t *testing.T) {
+	api := newFakeAPI(map[string]string{
+		"GET /accounts/account/workers/scripts": `{"success":true,"result":[]}`,
+		"POST /zones/zone/workers/routes":       `{"success":true,"result":{"id":"route"}}`,
+	})
+	c, _ := newTestClient(t, api, nil)
+
+	_, err := c.DeployToRoute(context.Background(), "zone", "example.com/*", &UploadInput{
+		Identifier:    "worker",
+		WrapperScript: []byte("export default {}"),
+		MainModule:    true,
+	})
+	if err == nil {
+		t.Fatal("DeployToRoute() error = nil, want the upload error")
+	}
+	if n := api.count("POST /zones/zone/workers/routes"); n != 0 {
+		t.Errorf("route requests = %d, want 0", n)
+	}
+}
@@ -0,0 +1,113 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"github.com/loopholelabs/cloudflare/pkg/cron"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"sort"
+	"time"
+)
+
+// ScheduleInfo is a worker's cron trigger along with the next time it fires.
+// Cloudflare does not report the next run, so it is computed from the expression
+// and is zero if the expression can't be parsed.
+type ScheduleInfo struct {
+	Cron       string
+	CreatedOn  string
+	ModifiedOn string
+	NextRun    time.Time
+}
+
+func (c *Cloudflare) GetCronTriggers(ctx context.Context, identifier string) ([]models.Schedule, error) {
+	res := new(models.SchedulesResponse)
+	err := c.doJSON(ctx, "GET", c.scriptURL(identifier)+"/schedules", nil, res, "GetCronTriggers", "getting worker schedules")
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Result.Schedules, nil
+}
+
+func (c *Cloudflare) GetFunctionSchedules(ctx context.Context, identifier string) ([]models.Schedule, error) {
+	return c.GetCronTriggers(ctx, identifier)
+}
+
+func (c *Cloudflare) GetSchedules(ctx context.Context, identifier string) ([]ScheduleInfo, error) {
+	schedules, err := c.GetCronTriggers(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	infos := make([]ScheduleInfo, 0, len(schedules))
+	for _, schedule := range schedules {
+		info := ScheduleInfo{
+			Cron:       schedule.Cron,
+			CreatedOn:  schedule.CreatedOn,
+			ModifiedOn: schedule.ModifiedOn,
+		}
+		parsed, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			c.log(ctx).Warn().Err(err).Str("identifier", identifier).Msg("unable to compute next run of worker schedule")
+		} else {
+			info.NextRun = parsed.Next(now)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (c *Cloudflare) PutCronTriggers(ctx context.Context, identifier string, crons []string) error {
+	schedules := make([]models.Schedule, 0, len(crons))
+	for _, expression := range crons {
+		err := cron.Validate(expression)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, models.Schedule{Cron: expression})
+	}
+
+	return c.doJSON(ctx, "PUT", c.scriptURL(identifier)+"/schedules", schedules, new(models.SchedulesResponse), "PutCronTriggers", "updating worker schedules")
+}
+
+// SetCronTriggersBatch replaces the cron triggers of every worker in schedules,
+// keyed by identifier, using up to concurrency concurrent requests. Results are
+// sorted by identifier, and a *BatchError is returned if any worker failed.
+func (c *Cloudflare) SetCronTriggersBatch(ctx context.Context, schedules map[string][]string, concurrency int) ([]BatchResult, error) {
+	identifiers := make([]string, 0, len(schedules))
+	for identifier := range schedules {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+	results := make([]BatchResult, len(identifiers))
+	for i := range results {
+		results[i].Identifier = identifiers[i]
+	}
+	started := runBatch(ctx, len(identifiers), concurrency, false, func(ctx context.Context, i int) error {
+		results[i].Err = c.PutCronTriggers(ctx, identifiers[i], schedules[identifiers[i]])
+		return results[i].Err
+	})
+	abortBatch(results[started:])
+	return results, batchError(results)
+}
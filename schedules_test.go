@@ -0,0 +1,87 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/cron"
+	"testing"
+)
+
+func TestPutCronTriggers(t *testing.T) {
+	tests := []struct {
+		name         string
+		crons        []string
+		wantErr      error
+		wantRequests int
+	}{
+		{name: "valid", crons: []string{"*/5 * * * *", "0 0 * * MON"}, wantRequests: 1},
+		{name: "none", crons: nil, wantRequests: 1},
+		{name: "invalid", crons: []string{"*/5 * * * *", "61 * * * *"}, wantErr: cron.ErrInvalidExpression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(map[string]string{
+				"PUT /accounts/account/workers/scripts/worker/schedules": `{"success":true,"result":{"schedules":[]}}`,
+			})
+			c, _ := newTestClient(t, api, nil)
+
+			err := c.PutCronTriggers(context.Background(), "worker", tt.crons)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PutCronTriggers() error = %v, want %v", err, tt.wantErr)
+			}
+			if n := api.count("PUT /accounts/account/workers/scripts/worker/schedules"); n != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", n, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestSetCronTriggersBatchCancelled(t *testing.T) {
+	api := newFakeAPI(map[string]string{
+		"PUT /accounts/account/workers/scripts/one/schedules": `{"success":true,"result":{"schedules":[]}}`,
+		"PUT /accounts/account/workers/scripts/two/schedules": `{"success":true,"result":{"schedules":[]}}`,
+	})
+	c, _ := newTestClient(t, api, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := c.SetCronTriggersBatch(ctx, map[string][]string{
+		"one": {"*/5 * * * *"},
+		"two": {"0 0 * * MON"},
+	}, 1)
+	if err == nil {
+		t.Fatal("SetCronTriggersBatch() error = nil, want an error")
+	}
+	want := []string{"one", "two"}
+	if len(results) != len(want) {
+		t.Fatalf("results = %d, want %d", len(results), len(want))
+	}
+	for i, result := range results {
+		if result.Identifier != want[i] {
+			t.Errorf("results[%d].Identifier = %q, want %q", i, result.Identifier, want[i])
+		}
+		if !errors.Is(result.Err, ErrBatchAborted) {
+			t.Errorf("results[%d].Err = %v, want %v", i, result.Err, ErrBatchAborted)
+		}
+	}
+	if len(api.requests) != 0 {
+		t.Errorf("requests = %v, want none", api.requests)
+	}
+}
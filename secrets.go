@@ -0,0 +1,85 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultSecretConcurrency = 4
+)
+
+func (c *Cloudflare) PutSecret(ctx context.Context, identifier string, name string, value string) error {
+	secret := &models.Secret{
+		Name: name,
+		Text: value,
+		Type: "secret_text",
+	}
+	return c.doJSON(ctx, "PUT", c.scriptURL(identifier)+"/secrets", secret, new(models.SecretResponse), "PutSecret", "updating worker secret")
+}
+
+// ListSecrets returns the name and type of each secret bound to the worker, secret
+// values can't be read back from Cloudflare
+func (c *Cloudflare) ListSecrets(ctx context.Context, identifier string) ([]models.Secret, error) {
+	res := new(models.SecretsResponse)
+	err := c.doJSON(ctx, "GET", c.scriptURL(identifier)+"/secrets", nil, res, "ListSecrets", "listing worker secrets")
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Result, nil
+}
+
+// PutSecrets updates all the given secrets using up to Options.SecretConcurrency
+// concurrent requests. Once ctx is done no further secrets are started, and those
+// fail with ErrBatchAborted. Secret values are never included in returned errors.
+func (c *Cloudflare) PutSecrets(ctx context.Context, identifier string, secrets map[string]string) error {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, len(names))
+	started := runBatch(ctx, len(names), c.options.secretConcurrency(), false, func(ctx context.Context, i int) error {
+		errs[i] = c.PutSecret(ctx, identifier, names[i], secrets[names[i]])
+		return errs[i]
+	})
+	for i := started; i < len(names); i++ {
+		errs[i] = ErrBatchAborted
+	}
+
+	var failed []string
+	var firstErr error
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, names[i])
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("error updating %d of %d secrets (%s): %w", len(failed), len(names), strings.Join(failed, ", "), firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPutSecrets(t *testing.T) {
+	tests := []struct {
+		name            string
+		concurrency     int
+		canceled        bool
+		wantMaxInFlight int64
+		wantRequests    int64
+		wantErr         error
+	}{
+		{name: "default concurrency", wantMaxInFlight: DefaultSecretConcurrency, wantRequests: 8},
+		{name: "configured concurrency", concurrency: 2, wantMaxInFlight: 2, wantRequests: 8},
+		{name: "serial", concurrency: 1, wantMaxInFlight: 1, wantRequests: 8},
+		{name: "canceled", canceled: true, wantRequests: 0, wantErr: ErrBatchAborted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, maxInFlight, requests int64
+			var mu sync.Mutex
+			var operations []string
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt64(&requests, 1)
+				n := atomic.AddInt64(&inFlight, 1)
+				for {
+					max := atomic.LoadInt64(&maxInFlight)
+					if n <= max || atomic.CompareAndSwapInt64(&maxInFlight, max, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt64(&inFlight, -1)
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}), func(options *Options) {
+				options.SecretConcurrency = tt.concurrency
+				options.ObserveFunc = func(observation Observation) {
+					mu.Lock()
+					operations = append(operations, observation.Operation)
+					mu.Unlock()
+				}
+			})
+
+			ctx := context.Background()
+			if tt.canceled {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(ctx)
+				cancel()
+			}
+			secrets := make(map[string]string)
+			for _, name := range []string{"A", "B", "C", "D", "E", "F", "G", "H"} {
+				secrets["SECRET_"+name] = "value-" + name
+			}
+			err := c.PutSecrets(ctx, "worker", secrets)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PutSecrets() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "value-") {
+				t.Errorf("error %q contains a secret value", err)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if maxInFlight != tt.wantMaxInFlight {
+				t.Errorf("max in flight = %d, want %d", maxInFlight, tt.wantMaxInFlight)
+			}
+			for _, operation := range operations {
+				if operation != "updating worker secret" {
+					t.Errorf("operation = %q, want %q", operation, "updating worker secret")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"mime/multipart"
+	"net/http"
+)
+
+type settingsPatch struct {
+	Limits *models.Limits `json:"limits,omitempty"`
+}
+
+func (c *Cloudflare) GetSettings(ctx context.Context, identifier string) (*models.ScriptSettings, error) {
+	res := new(models.SettingsResponse)
+	err := c.doJSON(ctx, "GET", c.scriptURL(identifier)+"/settings", nil, res, "GetSettings", "getting worker settings")
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
+
+func (c *Cloudflare) GetBindings(ctx context.Context, identifier string) ([]bindings.Worker, error) {
+	settings, err := c.GetSettings(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings.Bindings, nil
+}
+
+func (c *Cloudflare) GetLimits(ctx context.Context, identifier string) (int, error) {
+	settings, err := c.GetSettings(ctx, identifier)
+	if err != nil {
+		return 0, err
+	}
+
+	return settings.Limits.CPUMs, nil
+}
+
+func (c *Cloudflare) SetLimits(ctx context.Context, identifier string, cpuMs int) error {
+	settings, err := c.patchSettings(ctx, identifier, &settingsPatch{
+		Limits: &models.Limits{CPUMs: cpuMs},
+	})
+	if err != nil {
+		return err
+	}
+
+	if settings.Limits.CPUMs != cpuMs {
+		c.log(ctx).Warn().Str("identifier", identifier).Int("requested_cpu_ms", cpuMs).Int("applied_cpu_ms", settings.Limits.CPUMs).Msg("worker cpu limit was not applied as requested")
+	}
+
+	return nil
+}
+
+func (c *Cloudflare) patchSettings(ctx context.Context, identifier string, patch *settingsPatch) (*models.ScriptSettings, error) {
+	patchJSON, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling settings: %w", err)
+	}
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	err = addPart(writer, bindings.Part{
+		FieldName:   "settings",
+		FileName:    "settings.json",
+		ContentType: "application/json",
+		Content:     patchJSON,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error adding settings to multipart request: %w", err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error closing multipart writer: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(withOperation(ctx, "SetLimits", "updating worker settings"), "PATCH", c.scriptURL(identifier)+"/settings", body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating settings request: %w", err)
+	}
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	c.authorize(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error updating worker settings: %w", err)
+	}
+	defer resp.Body.Close()
+	res := new(models.SettingsResponse)
+	err = c.decodeResponse(resp, res, "updating worker settings")
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
@@ -0,0 +1,190 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"io"
+	"strings"
+)
+
+// FunctionSnapshot is everything ExportFunction can read back about a worker, and
+// is safe to marshal as JSON for backups. Secret values can't be read from
+// Cloudflare, so Secrets only lists their names.
+type FunctionSnapshot struct {
+	Identifier string                 `json:"identifier"`
+	Modules    []SnapshotModule       `json:"modules"`
+	Settings   *models.ScriptSettings `json:"settings"`
+	Schedules  []string               `json:"schedules"`
+	Domains    []models.Domain        `json:"domains,omitempty"`
+	Tags       []string               `json:"tags,omitempty"`
+	Secrets    []string               `json:"secrets,omitempty"`
+}
+
+// SnapshotModule is a single module of the worker's script. The first module of a
+// snapshot is the main module.
+type SnapshotModule struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Content     []byte `json:"content"`
+}
+
+// ExportFunction gathers the worker's script, settings, bindings, cron triggers,
+// custom domains and tags. Zone routes are not included, as listing them requires
+// the zone ID.
+func (c *Cloudflare) ExportFunction(ctx context.Context, identifier string) (*FunctionSnapshot, error) {
+	script, err := c.GetFunction(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &FunctionSnapshot{
+		Identifier: identifier,
+		Tags:       script.Tags,
+	}
+
+	mainModule, err := c.downloadModules(ctx, identifier, func(name string, contentType string, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("error reading module %s: %w", name, err)
+		}
+		snapshot.Modules = append(snapshot.Modules, SnapshotModule{
+			Name:        name,
+			ContentType: contentType,
+			Content:     content,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// multipart content isn't guaranteed to start with the main module
+	for i, module := range snapshot.Modules {
+		if module.Name == mainModule {
+			copy(snapshot.Modules[1:i+1], snapshot.Modules[:i])
+			snapshot.Modules[0] = module
+			break
+		}
+	}
+
+	snapshot.Settings, err = c.GetSettings(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range snapshot.Settings.Bindings {
+		if binding.Type == "secret_text" {
+			snapshot.Secrets = append(snapshot.Secrets, binding.Name)
+		}
+	}
+
+	schedules, err := c.GetCronTriggers(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Schedules = make([]string, 0, len(schedules))
+	for _, schedule := range schedules {
+		snapshot.Schedules = append(snapshot.Schedules, schedule.Cron)
+	}
+
+	snapshot.Domains, err = c.ListDomains(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// ImportFunction recreates the worker from snapshot, replacing it if it already
+// exists. Secrets already bound to an existing worker are kept, but secrets listed
+// in the snapshot must otherwise be set again with PutSecrets.
+func (c *Cloudflare) ImportFunction(ctx context.Context, snapshot *FunctionSnapshot) error {
+	if len(snapshot.Modules) == 0 {
+		return fmt.Errorf("%w: %s", ErrEmptySnapshot, snapshot.Identifier)
+	}
+
+	parts := make([]bindings.Part, 0, len(snapshot.Modules))
+	for _, module := range snapshot.Modules {
+		parts = append(parts, bindings.Part{
+			Name:        module.Name,
+			ContentType: module.ContentType,
+			Content:     module.Content,
+		})
+	}
+
+	metadata := &bindings.Metadata{
+		Bindings:     []bindings.Worker{},
+		KeepBindings: []string{"secret_text"},
+		Tags:         snapshot.Tags,
+	}
+	// service workers are a single plain javascript part, everything else is
+	// uploaded as modules
+	main := snapshot.Modules[0]
+	if len(snapshot.Modules) == 1 && strings.HasPrefix(main.ContentType, "application/javascript") && !strings.Contains(main.ContentType, "module") {
+		metadata.BodyPart = main.Name
+	} else {
+		metadata.MainModule = main.Name
+	}
+	if snapshot.Settings != nil {
+		metadata.CompatibilityDate = snapshot.Settings.CompatibilityDate
+		metadata.CompatibilityFlags = snapshot.Settings.CompatibilityFlags
+		for _, binding := range snapshot.Settings.Bindings {
+			if binding.Type != "secret_text" {
+				metadata.Bindings = append(metadata.Bindings, binding)
+			}
+		}
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("error marshaling metadata: %w", err)
+	}
+	_, err = c.UploadRaw(ctx, snapshot.Identifier, parts, metadataJSON)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.Settings != nil && snapshot.Settings.Limits.CPUMs > 0 {
+		err = c.SetLimits(ctx, snapshot.Identifier, snapshot.Settings.Limits.CPUMs)
+		if err != nil {
+			return err
+		}
+	}
+
+	if snapshot.Schedules != nil {
+		err = c.PutCronTriggers(ctx, snapshot.Identifier, snapshot.Schedules)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, domain := range snapshot.Domains {
+		_, err = c.AttachDomain(ctx, domain.ZoneID, domain.Hostname, snapshot.Identifier)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(snapshot.Secrets) > 0 {
+		c.log(ctx).Warn().Str("identifier", snapshot.Identifier).Strs("secrets", snapshot.Secrets).Msg("worker secrets are not included in snapshots and must be set again if missing")
+	}
+
+	return nil
+}
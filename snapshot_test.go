@@ -0,0 +1,74 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+func TestExportFunctionMainModule(t *testing.T) {
+	content := new(bytes.Buffer)
+	writer := multipart.NewWriter(content)
+	for _, module := range []struct{ name, content string }{
+		{name: "util.js", content: "export const util = 1"},
+		{name: "index.js", content: "export default {}"},
+	} {
+		part, err := writer.CreateFormFile(module.name, module.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = part.Write([]byte(module.content))
+	}
+	_ = writer.Close()
+
+	api := newFakeAPI(map[string]string{
+		"GET /accounts/account/workers/scripts":                  `{"success":true,"result":[{"id":"worker"}]}`,
+		"GET /accounts/account/workers/scripts/worker/settings":  `{"success":true,"result":{"bindings":[]}}`,
+		"GET /accounts/account/workers/scripts/worker/schedules": `{"success":true,"result":{"schedules":[]}}`,
+		"GET /accounts/account/workers/domains":                  `{"success":true,"result":[]}`,
+	})
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts/account/workers/scripts/worker/content/v2" {
+			api.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", writer.FormDataContentType())
+		w.Header().Set("CF-Entrypoint", "index.js")
+		_, _ = w.Write(content.Bytes())
+	}), nil)
+
+	snapshot, err := c.ExportFunction(context.Background(), "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"index.js", "util.js"}
+	if len(snapshot.Modules) != len(want) {
+		t.Fatalf("expected %d modules, got %d", len(want), len(snapshot.Modules))
+	}
+	for i, module := range snapshot.Modules {
+		if module.Name != want[i] {
+			t.Errorf("expected module %d to be %s, got %s", i, want[i], module.Name)
+		}
+	}
+	if string(snapshot.Modules[0].Content) != "export default {}" {
+		t.Errorf("unexpected main module content %q", snapshot.Modules[0].Content)
+	}
+}
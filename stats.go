@@ -0,0 +1,80 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+type requestStatsKey struct{}
+
+// requestStats accumulates the number of HTTP attempts made and the request bytes
+// sent for every request made with a context carrying it. Stats nest, so requests
+// are also counted by the stats of every parent context.
+type requestStats struct {
+	attempts  int64
+	bytesSent int64
+	parent    *requestStats
+}
+
+func withRequestStats(ctx context.Context) (context.Context, *requestStats) {
+	parent, _ := ctx.Value(requestStatsKey{}).(*requestStats)
+	stats := &requestStats{parent: parent}
+	return context.WithValue(ctx, requestStatsKey{}, stats), stats
+}
+
+func recordRequest(req *http.Request) {
+	stats, _ := req.Context().Value(requestStatsKey{}).(*requestStats)
+	if stats == nil {
+		return
+	}
+	for s := stats; s != nil; s = s.parent {
+		atomic.AddInt64(&s.attempts, 1)
+		if req.ContentLength > 0 {
+			atomic.AddInt64(&s.bytesSent, req.ContentLength)
+		}
+	}
+	// bodies of unknown length, such as transformed uploads, are counted as they
+	// are sent
+	if req.ContentLength < 0 && req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingBody{ReadCloser: req.Body, stats: stats}
+	}
+}
+
+type countingBody struct {
+	io.ReadCloser
+	stats *requestStats
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	for s := b.stats; s != nil; s = s.parent {
+		atomic.AddInt64(&s.bytesSent, int64(n))
+	}
+	return n, err
+}
+
+func (s *requestStats) Attempts() int {
+	return int(atomic.LoadInt64(&s.attempts))
+}
+
+func (s *requestStats) BytesSent() int64 {
+	return atomic.LoadInt64(&s.bytesSent)
+}
@@ -0,0 +1,132 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/http"
+)
+
+func (c *Cloudflare) GetWorkersSubdomain(ctx context.Context) (string, error) {
+	c.subdomainMu.Lock()
+	defer c.subdomainMu.Unlock()
+	if c.subdomain != "" {
+		return c.subdomain, nil
+	}
+
+	action := "getting workers subdomain"
+	resp, err := c.sendJSON(ctx, "GET", c.accountURL()+"/workers/subdomain", nil, "GetWorkersSubdomain", action)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSubdomainNotRegistered
+	}
+	res := new(models.AccountSubdomainResponse)
+	err = c.decodeResponse(resp, res, action)
+	if err != nil {
+		return "", err
+	}
+	if res.Result.Subdomain == "" {
+		return "", ErrSubdomainNotRegistered
+	}
+
+	c.subdomain = res.Result.Subdomain
+	return c.subdomain, nil
+}
+
+// GetAccountSubdomain reads the account's workers.dev subdomain from the API,
+// bypassing the value cached by GetWorkersSubdomain
+func (c *Cloudflare) GetAccountSubdomain(ctx context.Context) (string, error) {
+	c.subdomainMu.Lock()
+	c.subdomain = ""
+	c.subdomainMu.Unlock()
+	return c.GetWorkersSubdomain(ctx)
+}
+
+func (c *Cloudflare) SetAccountSubdomain(ctx context.Context, name string) error {
+	c.subdomainMu.Lock()
+	defer c.subdomainMu.Unlock()
+
+	action := "setting workers subdomain"
+	resp, err := c.sendJSON(ctx, "PUT", c.accountURL()+"/workers/subdomain", &models.AccountSubdomain{Subdomain: name}, "SetAccountSubdomain", action)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %q", ErrSubdomainTaken, name)
+	}
+	res := new(models.AccountSubdomainResponse)
+	err = c.decodeResponse(resp, res, action)
+	if err != nil {
+		return err
+	}
+
+	c.subdomain = res.Result.Subdomain
+	return nil
+}
+
+func (c *Cloudflare) DeleteAccountSubdomain(ctx context.Context) error {
+	c.subdomainMu.Lock()
+	defer c.subdomainMu.Unlock()
+
+	err := c.doJSON(ctx, "DELETE", c.accountURL()+"/workers/subdomain", nil, new(models.Response), "DeleteAccountSubdomain", "deleting workers subdomain")
+	if err != nil {
+		return err
+	}
+
+	c.subdomain = ""
+	return nil
+}
+
+// GetSubdomain reports whether the worker is enabled on the workers.dev subdomain
+func (c *Cloudflare) GetSubdomain(ctx context.Context, identifier string) (bool, error) {
+	res := new(models.ScriptSubdomainResponse)
+	err := c.doJSON(ctx, "GET", c.scriptURL(identifier)+"/subdomain", nil, res, "GetSubdomain", "getting worker subdomain")
+	if err != nil {
+		return false, err
+	}
+	return res.Result.Enabled, nil
+}
+
+func (c *Cloudflare) SetSubdomain(ctx context.Context, identifier string, enabled bool) error {
+	action := "disabling worker subdomain"
+	if enabled {
+		action = "enabling worker subdomain"
+	}
+	res := new(models.ScriptSubdomainResponse)
+	err := c.doJSON(ctx, "POST", c.scriptURL(identifier)+"/subdomain", &models.ScriptSubdomain{Enabled: enabled}, res, "SetSubdomain", action)
+	if err != nil {
+		return err
+	}
+	if res.Result.Enabled != enabled {
+		return fmt.Errorf("error %s: %w (enabled: %t)", action, ErrSubdomainNotUpdated, res.Result.Enabled)
+	}
+	return nil
+}
+
+func (c *Cloudflare) EnableSubdomain(ctx context.Context, identifier string) error {
+	return c.SetSubdomain(ctx, identifier, true)
+}
+
+func (c *Cloudflare) DisableSubdomain(ctx context.Context, identifier string) error {
+	return c.SetSubdomain(ctx, identifier, false)
+}
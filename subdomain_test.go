@@ -0,0 +1,103 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestSetSubdomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      func(c *Cloudflare) error
+		response string
+		status   int
+		want     bool
+		wantErr  error
+	}{
+		{
+			name:     "enable",
+			set:      func(c *Cloudflare) error { return c.EnableSubdomain(context.Background(), "worker") },
+			response: `{"success":true,"result":{"enabled":true}}`,
+			want:     true,
+		},
+		{
+			name:     "disable",
+			set:      func(c *Cloudflare) error { return c.DisableSubdomain(context.Background(), "worker") },
+			response: `{"success":true,"result":{"enabled":false}}`,
+			want:     false,
+		},
+		{
+			name:     "not updated",
+			set:      func(c *Cloudflare) error { return c.SetSubdomain(context.Background(), "worker", true) },
+			response: `{"success":true,"result":{"enabled":false}}`,
+			want:     true,
+			wantErr:  ErrSubdomainNotUpdated,
+		},
+		{
+			name:     "unsuccessful",
+			set:      func(c *Cloudflare) error { return c.SetSubdomain(context.Background(), "worker", false) },
+			response: `{"success":false,"errors":[{"code":10000,"message":"failed"}]}`,
+			status:   http.StatusBadRequest,
+			want:     false,
+			wantErr:  new(APIError),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				Enabled *bool `json:"enabled"`
+			}
+			var path string
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.Method + " " + r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = w.Write([]byte(tt.response))
+			}), nil)
+
+			err := tt.set(c)
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case *APIError:
+				if !errors.As(err, &want) {
+					t.Fatalf("error = %v, want an *APIError", err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Fatalf("error = %v, want %v", err, want)
+				}
+			}
+			if path != "POST /accounts/account/workers/scripts/worker/subdomain" {
+				t.Errorf("request = %q", path)
+			}
+			if body.Enabled == nil || *body.Enabled != tt.want {
+				t.Errorf("enabled was not sent as %t", tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	BuildIDTagPrefix = "build_id:"
+)
+
+// BuildIDTag returns the tag UploadInput.BuildID is stored as
+func BuildIDTag(buildID string) string {
+	return BuildIDTagPrefix + buildID
+}
+
+func (c *Cloudflare) GetTags(ctx context.Context, identifier string) ([]string, error) {
+	script, err := c.GetFunction(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return script.Tags, nil
+}
+
+// GetBuildID returns the build ID the worker was uploaded with, or an empty string
+// if it was uploaded without one
+func (c *Cloudflare) GetBuildID(ctx context.Context, identifier string) (string, error) {
+	tags, err := c.GetTags(ctx, identifier)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, BuildIDTagPrefix) {
+			return strings.TrimPrefix(tag, BuildIDTagPrefix), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,85 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestUploadTagsBuildID(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		buildID string
+		want    []string
+	}{
+		{name: "no build id", tags: []string{"team:edge"}, want: []string{"team:edge"}},
+		{name: "build id", buildID: "1234", want: []string{"build_id:1234"}},
+		{name: "appended to tags", tags: []string{"team:edge"}, buildID: "1234", want: []string{"team:edge", "build_id:1234"}},
+		{name: "replaces a build id tag", tags: []string{"build_id:1000", "team:edge"}, buildID: "1234", want: []string{"team:edge", "build_id:1234"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := append([]string(nil), tt.tags...)
+			got := uploadTags(&UploadInput{Tags: tags, BuildID: tt.buildID})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uploadTags() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tags, tt.tags) {
+				t.Errorf("UploadInput.Tags was modified: %v", tags)
+			}
+		})
+	}
+}
+
+func TestGetBuildID(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{
+			name:     "tagged",
+			response: `{"success":true,"result":[{"id":"other","tags":["build_id:1"]},{"id":"worker","tags":["team:edge","build_id:1234"]}]}`,
+			want:     "1234",
+		},
+		{
+			name:     "untagged",
+			response: `{"success":true,"result":[{"id":"worker","tags":["team:edge"]}]}`,
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, newFakeAPI(map[string]string{
+				"GET /accounts/account/workers/scripts": tt.response,
+			}), nil)
+
+			got, err := c.GetBuildID(context.Background(), "worker")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("GetBuildID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
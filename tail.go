@@ -0,0 +1,184 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/coder/websocket"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTailReconnectInterval    = time.Second
+	DefaultTailMaxReconnectInterval = time.Second * 30
+	tailProtocol                    = "trace-v1"
+	tailMaxMessageSize              = 16 << 20
+)
+
+type tailWriteError struct {
+	err error
+}
+
+func (e *tailWriteError) Error() string {
+	return fmt.Sprintf("error writing tail event: %s", e.err)
+}
+
+func (e *tailWriteError) Unwrap() error {
+	return e.err
+}
+
+func (c *Cloudflare) CreateTail(ctx context.Context, identifier string) (*models.Tail, error) {
+	res := new(models.TailResponse)
+	err := c.doJSON(ctx, "POST", c.scriptURL(identifier)+"/tails", nil, res, "CreateTail", "creating worker tail")
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
+
+func (c *Cloudflare) DeleteTail(ctx context.Context, identifier string, tailID string) error {
+	return c.doJSON(ctx, "DELETE", c.scriptURL(identifier)+"/tails/"+tailID, nil, new(models.Response), "DeleteTail", "deleting worker tail")
+}
+
+// TailTo writes every tail event for the worker to w as a line of JSON until ctx is
+// cancelled, creating a new tail session whenever the current one is disconnected.
+// Reconnects back off exponentially up to DefaultTailMaxReconnectInterval, and
+// client errors other than rate limiting are returned instead of retried.
+func (c *Cloudflare) TailTo(ctx context.Context, identifier string, w io.Writer) error {
+	attempt := 0
+	for {
+		started := time.Now()
+		err := c.tailTo(ctx, identifier, w)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c.ctx.Err() != nil {
+			return ErrClosed
+		}
+		var writeErr *tailWriteError
+		if errors.As(err, &writeErr) {
+			return err
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500 && apiErr.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+		// a session that stayed up for a while is not part of a run of failures
+		if time.Since(started) >= DefaultTailMaxReconnectInterval {
+			attempt = 0
+		}
+		delay := exponentialBackoff(DefaultTailReconnectInterval, DefaultTailMaxReconnectInterval, attempt)
+		attempt++
+		c.log(ctx).Warn().Err(c.redactErr(err)).Str("identifier", identifier).Dur("delay", delay).Msg("tail disconnected, reconnecting")
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-c.ctx.Done():
+			timer.Stop()
+			return ErrClosed
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Cloudflare) tailTo(ctx context.Context, identifier string, w io.Writer) error {
+	tail, err := c.CreateTail(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		deleteCtx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		if err := c.DeleteTail(deleteCtx, identifier, tail.ID); err != nil {
+			c.log(ctx).Debug().Err(c.redactErr(err)).Str("identifier", identifier).Str("tail", tail.ID).Msg("error deleting tail")
+		}
+	}()
+
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			cancel()
+		case <-connCtx.Done():
+		}
+	}()
+
+	conn, err := c.dialTail(connCtx, tail.URL)
+	if err != nil {
+		return err
+	}
+	defer conn.CloseNow()
+
+	err = conn.Write(connCtx, websocket.MessageText, []byte(`{"filters":[],"debug":false}`))
+	if err != nil {
+		return fmt.Errorf("error sending tail filters: %w", err)
+	}
+
+	line := new(bytes.Buffer)
+	for {
+		_, message, err := conn.Read(connCtx)
+		if err != nil {
+			if websocket.CloseStatus(err) != -1 {
+				err = io.EOF
+			}
+			return fmt.Errorf("error reading tail event: %w", err)
+		}
+		line.Reset()
+		if json.Compact(line, message) != nil {
+			line.Reset()
+			line.Write(message)
+		}
+		line.WriteByte('\n')
+		_, err = w.Write(line.Bytes())
+		if err != nil {
+			return &tailWriteError{err: err}
+		}
+	}
+}
+
+// dialTail connects to a tail session through the same dialer, client certificates
+// and Limiter rate as API requests. The API token is never sent, and the session
+// does not hold one of the Limiter's concurrency slots since it is long-lived.
+func (c *Cloudflare) dialTail(ctx context.Context, tailURL string) (*websocket.Conn, error) {
+	if c.options.Limiter != nil {
+		err := c.options.Limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	conn, _, err := websocket.Dial(ctx, tailURL, &websocket.DialOptions{
+		HTTPClient:   c.tailClient,
+		Subprotocols: []string{tailProtocol},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error dialing tail: %w", err)
+	}
+	conn.SetReadLimit(tailMaxMessageSize)
+	return conn, nil
+}
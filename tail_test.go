@@ -0,0 +1,190 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/coder/websocket"
+	"github.com/rs/zerolog"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTailTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []string
+		want     string
+	}{
+		{
+			name: "no events",
+		},
+		{
+			name:     "json events are compacted",
+			messages: []string{"{\n  \"outcome\": \"ok\"\n}", `{"outcome":"exception"}`},
+			want:     "{\"outcome\":\"ok\"}\n{\"outcome\":\"exception\"}\n",
+		},
+		{
+			name:     "non json events are written as is",
+			messages: []string{"not json"},
+			want:     "not json\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var authorization, filters, protocol string
+			tailServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{tailProtocol}})
+				if err != nil {
+					return
+				}
+				defer conn.CloseNow()
+				protocol = conn.Subprotocol()
+				_, message, err := conn.Read(r.Context())
+				if err != nil {
+					return
+				}
+				filters = string(message)
+				for _, message := range test.messages {
+					if conn.Write(r.Context(), websocket.MessageText, []byte(message)) != nil {
+						return
+					}
+				}
+				_ = conn.Close(websocket.StatusNormalClosure, "")
+			}))
+			t.Cleanup(tailServer.Close)
+
+			api := newFakeAPI(map[string]string{
+				"POST /accounts/account/workers/scripts/worker/tails":        `{"success":true,"result":{"id":"tail","url":"ws` + strings.TrimPrefix(tailServer.URL, "http") + `"}}`,
+				"DELETE /accounts/account/workers/scripts/worker/tails/tail": `{"success":true}`,
+			})
+			var dials int32
+			c, _ := newTestClient(t, api, func(options *Options) {
+				options.Limiter = NewLimiter(1000, 1, 1)
+				options.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+					atomic.AddInt32(&dials, 1)
+					return new(net.Dialer).DialContext(ctx, network, address)
+				}
+			})
+
+			out := new(bytes.Buffer)
+			err := c.tailTo(context.Background(), "worker", out)
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("expected io.EOF once the session closed, got %v", err)
+			}
+			if out.String() != test.want {
+				t.Errorf("expected output %q, got %q", test.want, out.String())
+			}
+			if authorization != "" {
+				t.Errorf("expected no Authorization header on the tail session, got %q", authorization)
+			}
+			if protocol != tailProtocol {
+				t.Errorf("expected subprotocol %q, got %q", tailProtocol, protocol)
+			}
+			if filters != `{"filters":[],"debug":false}` {
+				t.Errorf("unexpected filters %q", filters)
+			}
+			if atomic.LoadInt32(&dials) < 2 {
+				t.Errorf("expected the API and tail connections to use the configured dialer, got %d dials", dials)
+			}
+			if api.count("DELETE /accounts/account/workers/scripts/worker/tails/tail") != 1 {
+				t.Errorf("expected the tail to be deleted")
+			}
+		})
+	}
+}
+
+func TestTailToWriteError(t *testing.T) {
+	tailServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		_, _, _ = conn.Read(r.Context())
+		_ = conn.Write(r.Context(), websocket.MessageText, []byte(`{}`))
+		_, _, _ = conn.Read(r.Context())
+	}))
+	t.Cleanup(tailServer.Close)
+
+	api := newFakeAPI(map[string]string{
+		"POST /accounts/account/workers/scripts/worker/tails":        `{"success":true,"result":{"id":"tail","url":"ws` + strings.TrimPrefix(tailServer.URL, "http") + `"}}`,
+		"DELETE /accounts/account/workers/scripts/worker/tails/tail": `{"success":true}`,
+	})
+	c, _ := newTestClient(t, api, nil)
+
+	errWrite := errors.New("write failed")
+	err := c.TailTo(context.Background(), "worker", writerFunc(func([]byte) (int, error) {
+		return 0, errWrite
+	}))
+	if !errors.Is(err, errWrite) {
+		t.Fatalf("expected the write error to stop tailing, got %v", err)
+	}
+	if n := api.count("POST /accounts/account/workers/scripts/worker/tails"); n != 1 {
+		t.Errorf("expected a single tail session, got %d", n)
+	}
+}
+
+func TestTailToClientError(t *testing.T) {
+	api := newFakeAPI(nil)
+	c, _ := newTestClient(t, api, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	err := c.TailTo(ctx, "worker", io.Discard)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the 404 to stop tailing, got %v", err)
+	}
+	if n := api.count("POST /accounts/account/workers/scripts/worker/tails"); n != 1 {
+		t.Errorf("expected a single tail session, got %d", n)
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestNewTailTransport(t *testing.T) {
+	logger := zerolog.Nop()
+	transport, err := newTailTransport(&Options{ClientCertificates: []tls.Certificate{{}}}, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.TLSClientConfig.Certificates) != 1 {
+		t.Errorf("expected the tail transport to present the client certificate, got %d", len(transport.TLSClientConfig.Certificates))
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Errorf("expected HTTP/2 to be disabled")
+	}
+	if len(transport.TLSClientConfig.NextProtos) != 1 || transport.TLSClientConfig.NextProtos[0] != "http/1.1" {
+		t.Errorf("expected only http/1.1 to be negotiated, got %v", transport.TLSClientConfig.NextProtos)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/rs/zerolog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultDialRetryInterval = time.Millisecond * 250
+)
+
+func newTransport(options *Options, logger *zerolog.Logger) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	certificates := options.ClientCertificates
+	if options.ClientCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(options.ClientCertFile, options.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		certificates = append(append([]tls.Certificate(nil), certificates...), certificate)
+	}
+	if len(certificates) > 0 {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = new(tls.Config)
+		}
+		transport.TLSClientConfig.Certificates = certificates
+	}
+	// AuthTransport negotiates and decodes compression itself
+	transport.DisableCompression = true
+	dial := options.DialContext
+	if dial == nil {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			LocalAddr: options.LocalAddr,
+		}
+		dial = dialer.DialContext
+	}
+	if options.ForceIPv4 {
+		dial = forceIPv4DialContext(dial)
+	}
+	if options.DialRetries > 0 {
+		interval := options.DialRetryInterval
+		if interval <= 0 {
+			interval = DefaultDialRetryInterval
+		}
+		dial = retryDialContext(dial, options.DialRetries, interval, logger)
+	}
+	transport.DialContext = dial
+	return transport, nil
+}
+
+// newProbeTransport returns a transport for requests to deployed workers, which
+// shares the dial options of the API transport but never presents the client
+// certificates meant for the API
+func newProbeTransport(options *Options, logger *zerolog.Logger) (*http.Transport, error) {
+	probeOptions := *options
+	probeOptions.ClientCertificates = nil
+	probeOptions.ClientCertFile = ""
+	probeOptions.ClientKeyFile = ""
+	transport, err := newTransport(&probeOptions, logger)
+	if err != nil {
+		return nil, err
+	}
+	transport.DisableCompression = false
+	return transport, nil
+}
+
+// newTailTransport returns a transport for tail sessions, which shares the dial
+// options and client certificates of the API transport but only speaks HTTP/1.1,
+// since websocket upgrades are not possible over HTTP/2
+func newTailTransport(options *Options, logger *zerolog.Logger) (*http.Transport, error) {
+	transport, err := newTransport(options, logger)
+	if err != nil {
+		return nil, err
+	}
+	transport.ForceAttemptHTTP2 = false
+	transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = new(tls.Config)
+	}
+	transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	return transport, nil
+}
+
+type dialContextFunc func(ctx context.Context, network string, address string) (net.Conn, error)
+
+func forceIPv4DialContext(dial dialContextFunc) dialContextFunc {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		if network == "tcp" || network == "tcp6" {
+			network = "tcp4"
+		}
+		return dial(ctx, network, address)
+	}
+}
+
+func retryDialContext(dial dialContextFunc, retries int, interval time.Duration, logger *zerolog.Logger) dialContextFunc {
+	return func(ctx context.Context, network string, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		for attempt := 1; err != nil && attempt <= retries; attempt++ {
+			logger.Debug().Err(err).Str("address", address).Int("attempt", attempt).Msg("retrying dial")
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+			conn, err = dial(ctx, network, address)
+		}
+		return conn, err
+	}
+}
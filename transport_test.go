@@ -0,0 +1,83 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTransportClientCertificates(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	tests := []struct {
+		name      string
+		options   Options
+		wantCerts int
+		wantErr   error
+	}{
+		{name: "none", wantCerts: 0},
+		{name: "certificates", options: Options{ClientCertificates: []tls.Certificate{{}, {}}}, wantCerts: 2},
+		{name: "missing files", options: Options{ClientCertFile: missing, ClientKeyFile: missing}, wantErr: os.ErrNotExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			transport, err := newTransport(&tt.options, &logger)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("newTransport() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var certs int
+			if transport.TLSClientConfig != nil {
+				certs = len(transport.TLSClientConfig.Certificates)
+			}
+			if certs != tt.wantCerts {
+				t.Errorf("certificates = %d, want %d", certs, tt.wantCerts)
+			}
+		})
+	}
+}
+
+func TestOptionsValidateClientCertificate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		wantErr error
+	}{
+		{name: "neither"},
+		{name: "both", cert: "cert.pem", key: "key.pem"},
+		{name: "cert only", cert: "cert.pem", wantErr: ErrIncompleteClientCertificate},
+		{name: "key only", key: "key.pem", wantErr: ErrIncompleteClientCertificate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &Options{UserID: "account", Token: "token", ClientCertFile: tt.cert, ClientKeyFile: tt.key}
+			if err := options.Validate(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,482 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type UploadInput struct {
+	Identifier         string
+	WrapperScript      []byte
+	EntrypointName     string
+	MainModule         bool
+	Functions          []*bindings.Function
+	Files              []bindings.File
+	Modules            []bindings.Module
+	Bindings           []bindings.Worker
+	CompatibilityDate  string
+	CompatibilityFlags []string
+	ExpectedHandlers   []string
+	KeepBindings       []string
+	DeployMessage      string
+	Assets             *bindings.Assets
+	Observability      *bindings.Observability
+	Migrations         *bindings.Migrations
+	Tags               []string
+	UsageModel         string
+
+	// SkipSubdomain leaves the workers.dev subdomain untouched. Unless RouteOnly or
+	// UploadDisabled is also set, the upload then omits the subdomain availability
+	// query and UploadedFunction.SubdomainEnabled is always false
+	SkipSubdomain bool
+
+	// IncludeScript returns the deployed script in UploadedFunction.Script, which
+	// is otherwise excluded from the upload response to keep it small
+	IncludeScript bool
+
+	// AllowUnknownCompatibilityFlags skips checking CompatibilityFlags against the
+	// known flags, for flags released after this package
+	AllowUnknownCompatibilityFlags bool
+
+	// CompatibilityPreset names a preset in Options.CompatibilityPresets to expand
+	// into CompatibilityDate and CompatibilityFlags
+	CompatibilityPreset string
+
+	// RouteOnly deploys a worker that is only reachable through routes or custom
+	// domains. The upload fails unless the worker has a custom domain or a route in
+	// one of RouteZoneIDs, and its workers.dev subdomain is disabled.
+	RouteOnly    bool
+	RouteZoneIDs []string
+
+	// UploadDisabled uploads the worker without serving it on its workers.dev
+	// subdomain, disabling the subdomain if it was enabled, so that a set of workers
+	// can be verified and then enabled together with BulkEnable
+	UploadDisabled bool
+
+	// PreBundled asserts that WrapperScript is a fully bundled ES module that must be
+	// uploaded as-is. It implies MainModule, and the upload is rejected if it would
+	// contain any part other than the entrypoint. The metadata produced has
+	// main_module set to the entrypoint name and no body_part.
+	PreBundled bool
+
+	// Python uploads WrapperScript as a Python main module, named
+	// DefaultPythonEntrypointName unless EntrypointName is set, and adds the
+	// python_workers compatibility flag if it is missing. It implies MainModule.
+	Python bool
+
+	// BuildID is stored as a BuildIDTagPrefix tag, replacing any build ID tag in
+	// Tags, so the deployed worker can be traced back to the build with GetBuildID
+	BuildID string
+
+	ProgressFunc ProgressFunc
+}
+
+func (c *Cloudflare) UploadFunction(identifier string, wrapperScript []byte, functions []*bindings.Function) (*bindings.UploadedFunction, error) {
+	return c.Upload(c.ctx, &UploadInput{
+		Identifier:    identifier,
+		WrapperScript: wrapperScript,
+		Functions:     functions,
+	})
+}
+
+func (c *Cloudflare) Upload(ctx context.Context, input *UploadInput) (*bindings.UploadedFunction, error) {
+	ctx, stats := withRequestStats(ctx)
+	if input.ProgressFunc != nil {
+		ctx = withProgress(ctx, input.ProgressFunc)
+	}
+	identifier := input.Identifier
+	input, unlock, err := c.prepareUpload(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	parts, metadata, err := assembleUpload(input)
+	if err != nil {
+		return nil, err
+	}
+	manifest := bindings.NewUploadManifest(c.options.Prefix+identifier, parts, metadata)
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling metadata: %w", err)
+	}
+
+	if input.RouteOnly {
+		err = c.checkRouted(ctx, identifier, input.RouteZoneIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	manageSubdomain := managesSubdomain(input)
+	result, err := c.uploadParts(ctx, identifier, parts, metadataJSON, input.IncludeScript, manageSubdomain)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, handler := range input.ExpectedHandlers {
+		if !containsString(result.Handlers, handler) {
+			return nil, fmt.Errorf("%w %q (deployed handlers: %v)", ErrMissingHandler, handler, result.Handlers)
+		}
+	}
+
+	subdomainEnabled := manageSubdomain && result.AvailableOnSubdomain
+	var subdomainErr error
+	switch {
+	case !manageSubdomain:
+		// availability was not requested, so there is nothing to reconcile
+	case input.RouteOnly || input.UploadDisabled:
+		if result.AvailableOnSubdomain {
+			err = c.SetSubdomain(ctx, identifier, false)
+			if err != nil {
+				return nil, err
+			}
+			subdomainEnabled = false
+		}
+	case !result.AvailableOnSubdomain:
+		err = c.SetSubdomain(ctx, identifier, true)
+		if err != nil {
+			if !c.options.SubdomainBestEffort {
+				return nil, err
+			}
+			c.log(ctx).Warn().Err(c.redactErr(err)).Str("identifier", identifier).Msg("failed to enable worker subdomain")
+			subdomainErr = err
+		} else {
+			subdomainEnabled = true
+		}
+	}
+
+	// Cloudflare falls back to the account's default usage model instead of
+	// rejecting one the plan doesn't allow
+	var usageModelErr error
+	if input.UsageModel != "" && result.UsageModel != "" && !strings.EqualFold(input.UsageModel, result.UsageModel) {
+		usageModelErr = fmt.Errorf("%w: requested %s, applied %s", ErrUsageModelNotApplied, input.UsageModel, result.UsageModel)
+		c.log(ctx).Warn().Str("identifier", identifier).Str("requested_usage_model", input.UsageModel).Str("applied_usage_model", result.UsageModel).Msg("worker usage model was not applied as requested")
+	}
+
+	return &bindings.UploadedFunction{
+		Identifier:       identifier,
+		Subdomain:        c.options.Prefix + identifier,
+		SubdomainEnabled: subdomainEnabled,
+		SubdomainError:   subdomainErr,
+		UsageModel:       result.UsageModel,
+		UsageModelError:  usageModelErr,
+		Manifest:         manifest,
+		Script:           result.Script,
+		BytesSent:        stats.BytesSent(),
+		Attempts:         stats.Attempts(),
+	}, nil
+}
+
+// prepareUpload runs the checks shared by every upload of input, before anything
+// is sent, and returns input with its compatibility preset applied. On success the
+// worker is locked until the returned unlock is called.
+func (c *Cloudflare) prepareUpload(ctx context.Context, input *UploadInput) (*UploadInput, func(), error) {
+	err := c.checkReservedName(input.Identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	input, err = c.applyCompatibilityPreset(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	unlock, err := c.lockIdentifier(ctx, input.Identifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = c.checkCompatibilityFlags(ctx, input.Identifier, input.CompatibilityFlags, input.AllowUnknownCompatibilityFlags)
+	if err == nil {
+		err = c.checkWrapper(ctx, input)
+	}
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return input, unlock, nil
+}
+
+// UploadRaw uploads exactly the given parts and metadata, without any of the
+// binding assembly or post-upload steps performed by Upload
+func (c *Cloudflare) UploadRaw(ctx context.Context, identifier string, parts []bindings.Part, metadata json.RawMessage) (*models.ResponseResult, error) {
+	if !json.Valid(metadata) {
+		return nil, ErrInvalidMetadata
+	}
+
+	err := c.checkReservedName(identifier)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := c.lockIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return c.uploadParts(ctx, identifier, parts, metadata, false, true)
+}
+
+func uploadTags(input *UploadInput) []string {
+	if input.BuildID == "" {
+		return input.Tags
+	}
+
+	tags := make([]string, 0, len(input.Tags)+1)
+	for _, tag := range input.Tags {
+		if !strings.HasPrefix(tag, BuildIDTagPrefix) {
+			tags = append(tags, tag)
+		}
+	}
+	return append(tags, BuildIDTag(input.BuildID))
+}
+
+// managesSubdomain reports whether Upload reconciles the worker's workers.dev subdomain
+func managesSubdomain(input *UploadInput) bool {
+	return input.RouteOnly || input.UploadDisabled || !input.SkipSubdomain
+}
+
+func assembleUpload(input *UploadInput) ([]bindings.Part, *bindings.Metadata, error) {
+	if input.Assets != nil {
+		if err := input.Assets.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if input.Migrations != nil {
+		if err := input.Migrations.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if input.Observability != nil {
+		if err := input.Observability.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	functions := input.Functions
+	for _, function := range functions {
+		for _, binding := range function.Bindings() {
+			if !binding.IsEnabled() {
+				continue
+			}
+			if err := binding.Validate(); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s binding for function %s: %w", binding.Worker().Type, function.Identifier, err)
+			}
+		}
+	}
+
+	mainModule := input.MainModule || input.PreBundled || input.Python
+	if len(input.Modules) > 0 && !mainModule {
+		return nil, nil, ErrModulesRequireMainModule
+	}
+	for i := range input.Modules {
+		if err := input.Modules[i].Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	wrapperScriptContentType := "application/javascript"
+	if mainModule {
+		wrapperScriptContentType = "application/javascript+module"
+	}
+	entrypointName := input.EntrypointName
+	if entrypointName == "" {
+		entrypointName = DefaultEntrypointName
+	}
+	compatibilityFlags := input.CompatibilityFlags
+	if input.Python {
+		wrapperScriptContentType = "text/x-python"
+		if input.EntrypointName == "" {
+			entrypointName = DefaultPythonEntrypointName
+		}
+		if !containsString(compatibilityFlags, pythonWorkersFlag) {
+			compatibilityFlags = append(append(make([]string, 0, len(compatibilityFlags)+1), compatibilityFlags...), pythonWorkersFlag)
+		}
+	}
+	parts := []bindings.Part{{
+		Name:        entrypointName,
+		ContentType: wrapperScriptContentType,
+		Content:     input.WrapperScript,
+	}}
+
+	for i := range input.Modules {
+		parts = append(parts, input.Modules[i].Part())
+	}
+
+	for _, file := range input.Files {
+		if !file.IsEnabled() {
+			continue
+		}
+		parts = append(parts, bindings.Part{
+			Name:        fmt.Sprintf("%s.%s", file.Binding, file.Extension),
+			ContentType: file.ContentType,
+			Content:     file.Content,
+		})
+	}
+
+	for _, function := range functions {
+		parts = append(parts, bindings.Part{
+			Name:        fmt.Sprintf("%s.bin", function.Identifier),
+			ContentType: "application/octet-stream",
+			Content:     function.Source,
+		})
+
+		for _, file := range function.Files {
+			if !file.IsEnabled() {
+				continue
+			}
+			parts = append(parts, bindings.Part{
+				Name:        fmt.Sprintf("%s.%s.%s", function.Identifier, file.Binding, file.Extension),
+				ContentType: file.ContentType,
+				Content:     file.Content,
+			})
+		}
+	}
+
+	partNames := make(map[string]struct{}, len(parts))
+	for _, part := range parts {
+		name := part.FormFieldName()
+		if _, ok := partNames[name]; ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrDuplicatePart, name)
+		}
+		partNames[name] = struct{}{}
+	}
+
+	if input.PreBundled && len(parts) > 1 {
+		return nil, nil, fmt.Errorf("%w (found %d parts)", ErrPreBundledExtraParts, len(parts))
+	}
+
+	workers := make([]bindings.Worker, 0, len(functions)*2+len(input.Files)+len(input.Bindings))
+	for _, file := range input.Files {
+		if !file.IsEnabled() {
+			continue
+		}
+		workers = append(workers, bindings.Worker{
+			Type: file.Type,
+			Name: file.Binding,
+			Part: fmt.Sprintf("%s.%s", file.Binding, file.Extension),
+		})
+	}
+	for _, binding := range input.Bindings {
+		if binding.IsEnabled() {
+			workers = append(workers, binding)
+		}
+	}
+
+	for _, function := range functions {
+		workers = append(workers, bindings.Worker{
+			Type: "data_blob",
+			Name: fmt.Sprintf("__SF_%s", function.Identifier),
+			Part: fmt.Sprintf("%s.bin", function.Identifier),
+		})
+
+		for _, file := range function.Files {
+			if !file.IsEnabled() {
+				continue
+			}
+			workers = append(workers, bindings.Worker{
+				Type: file.Type,
+				Name: fmt.Sprintf("__%s_%s", file.Binding, function.Identifier),
+				Part: fmt.Sprintf("%s.%s.%s", function.Identifier, file.Binding, file.Extension),
+			})
+		}
+
+		for _, binding := range function.Bindings() {
+			if binding.IsEnabled() {
+				workers = append(workers, binding.Worker())
+			}
+		}
+
+		names := make([]string, 0, len(function.Vars))
+		for name := range function.Vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			workers = append(workers, bindings.Worker{
+				Type: "plain_text",
+				Name: name,
+				Text: function.Vars[name],
+			})
+		}
+	}
+
+	sort.SliceStable(workers, func(i, j int) bool {
+		return workers[i].Name < workers[j].Name
+	})
+	if err := ValidateBindings(workers); err != nil {
+		return nil, nil, err
+	}
+
+	metadata := &bindings.Metadata{
+		Bindings:           workers,
+		KeepBindings:       input.KeepBindings,
+		CompatibilityDate:  input.CompatibilityDate,
+		CompatibilityFlags: compatibilityFlags,
+		Assets:             input.Assets,
+		Tags:               uploadTags(input),
+		Observability:      input.Observability,
+		Migrations:         input.Migrations,
+		UsageModel:         input.UsageModel,
+	}
+	if input.DeployMessage != "" {
+		metadata.Annotations = &bindings.Annotations{
+			Message: input.DeployMessage,
+		}
+	}
+	if mainModule {
+		metadata.MainModule = entrypointName
+	} else {
+		metadata.BodyPart = entrypointName
+	}
+
+	return parts, metadata, nil
+}
+
+func (c *Cloudflare) uploadParts(ctx context.Context, identifier string, parts []bindings.Part, metadataJSON []byte, includeScript bool, includeSubdomainAvailability bool) (*models.ResponseResult, error) {
+	query := url.Values{}
+	if includeSubdomainAvailability {
+		query.Set("include_subdomain_availability", "true")
+	}
+	query.Set("excludeScript", strconv.FormatBool(!includeScript))
+	requestURL := c.scriptURL(identifier) + "?" + query.Encode()
+	req, err := c.newMultipartRequest(withOperation(ctx, "Upload", "uploading worker"), "PUT", requestURL, parts, metadataJSON)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error uploading worker: %w", err)
+	}
+	defer resp.Body.Close()
+	res := new(models.UploadResponse)
+	err = c.decodeResponse(resp, res, "uploading worker")
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
@@ -0,0 +1,837 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUploadPreChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   UploadInput
+		wantErr error
+	}{
+		{
+			name:    "reserved name",
+			input:   UploadInput{Identifier: "reserved", WrapperScript: []byte("export default {}")},
+			wantErr: ErrReservedName,
+		},
+		{
+			name:    "unknown preset",
+			input:   UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), CompatibilityPreset: "missing"},
+			wantErr: ErrUnknownCompatibilityPreset,
+		},
+		{
+			name:    "unknown compatibility flag",
+			input:   UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), CompatibilityFlags: []string{"not_a_flag"}},
+			wantErr: ErrUnknownCompatibilityFlag,
+		},
+		{
+			name:    "stale wrapper",
+			input:   UploadInput{Identifier: "worker", WrapperScript: []byte(`globalThis["__SF_missing"]`)},
+			wantErr: ErrStaleWrapper,
+		},
+		{
+			name:    "locked",
+			input:   UploadInput{Identifier: "locked", WrapperScript: []byte("export default {}")},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	uploads := map[string]func(c *Cloudflare, ctx context.Context, input *UploadInput) error{
+		"Upload": func(c *Cloudflare, ctx context.Context, input *UploadInput) error {
+			_, err := c.Upload(ctx, input)
+			return err
+		},
+		"CreateVersion": func(c *Cloudflare, ctx context.Context, input *UploadInput) error {
+			_, err := c.CreateVersion(ctx, input, "", "")
+			return err
+		},
+	}
+
+	for method, upload := range uploads {
+		for _, tt := range tests {
+			t.Run(method+"/"+tt.name, func(t *testing.T) {
+				var requests int64
+				c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					atomic.AddInt64(&requests, 1)
+					w.WriteHeader(http.StatusInternalServerError)
+				}), func(options *Options) {
+					options.IsReservedName = ReservedNames("reserved")
+					options.StrictCompatibilityFlags = true
+					options.StrictWrapperReferences = true
+					options.SerializeDeploys = true
+				})
+				unlock, err := c.lockIdentifier(context.Background(), "locked")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer unlock()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				input := tt.input
+				err = upload(c, ctx, &input)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("%s() error = %v, want %v", method, err, tt.wantErr)
+				}
+				if requests != 0 {
+					t.Errorf("%d requests were sent", requests)
+				}
+			})
+		}
+	}
+}
+
+func TestAssembleUploadFunctionBindings(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name      string
+		function  bindings.Function
+		wantTypes map[string]string
+		wantErr   error
+	}{
+		{
+			name: "enabled bindings",
+			function: bindings.Function{
+				Identifier:         "fn",
+				RateLimits:         []bindings.RateLimit{{Name: "LIMIT", NamespaceID: "1", Limit: 10, Period: 60}},
+				HyperdriveBindings: []bindings.Hyperdrive{{Name: "DB", ID: "0123456789abcdef0123456789abcdef"}},
+				DispatchNamespaces: []bindings.DispatchNamespace{{Name: "DISPATCH", Namespace: "tenants"}},
+				SecretsStore:       []bindings.SecretsStoreBinding{{Name: "KEY", StoreID: "store", SecretName: "key"}},
+			},
+			wantTypes: map[string]string{"LIMIT": "ratelimit", "DB": "hyperdrive", "DISPATCH": "dispatch_namespace", "KEY": "secrets_store_secret"},
+		},
+		{
+			name: "disabled invalid binding is skipped",
+			function: bindings.Function{
+				Identifier: "fn",
+				RateLimits: []bindings.RateLimit{{Name: "LIMIT", Enabled: &disabled}},
+			},
+			wantTypes: map[string]string{},
+		},
+		{
+			name: "invalid rate limit",
+			function: bindings.Function{
+				Identifier: "fn",
+				RateLimits: []bindings.RateLimit{{Name: "LIMIT", NamespaceID: "1", Limit: 10, Period: 30}},
+			},
+			wantErr: bindings.ErrInvalidRateLimitPeriod,
+		},
+		{
+			name: "invalid hyperdrive",
+			function: bindings.Function{
+				Identifier:         "fn",
+				HyperdriveBindings: []bindings.Hyperdrive{{Name: "DB", ID: "short"}},
+			},
+			wantErr: bindings.ErrInvalidHyperdriveID,
+		},
+		{
+			name: "invalid secrets store",
+			function: bindings.Function{
+				Identifier:   "fn",
+				SecretsStore: []bindings.SecretsStoreBinding{{Name: "KEY", StoreID: "store"}},
+			},
+			wantErr: bindings.ErrSecretsStoreSecretNameRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			function := tt.function
+			_, metadata, err := assembleUpload(&UploadInput{Identifier: "worker", WrapperScript: []byte("x"), Functions: []*bindings.Function{&function}})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("assembleUpload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := make(map[string]string)
+			for _, worker := range metadata.Bindings {
+				if worker.Type != "data_blob" {
+					got[worker.Name] = worker.Type
+				}
+			}
+			if len(got) != len(tt.wantTypes) {
+				t.Fatalf("bindings = %v, want %v", got, tt.wantTypes)
+			}
+			for name, typ := range tt.wantTypes {
+				if got[name] != typ {
+					t.Errorf("binding %s type = %q, want %q", name, got[name], typ)
+				}
+			}
+		})
+	}
+}
+
+func TestAssembleUploadKeepBindings(t *testing.T) {
+	tests := []struct {
+		name         string
+		keepBindings []string
+		want         string
+	}{
+		{name: "unset", want: ""},
+		{name: "secrets", keepBindings: []string{"secret_text"}, want: `"keep_bindings":["secret_text"]`},
+		{name: "several types", keepBindings: []string{"secret_text", "kv_namespace"}, want: `"keep_bindings":["secret_text","kv_namespace"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, metadata, err := assembleUpload(&UploadInput{Identifier: "worker", WrapperScript: []byte("x"), KeepBindings: tt.keepBindings})
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if strings.Contains(string(data), "keep_bindings") {
+					t.Errorf("metadata = %s, want no keep_bindings", data)
+				}
+				return
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("metadata = %s, want it to contain %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssembleUploadFunctionVars(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      map[string]string
+		wantNames []string
+	}{
+		{name: "none", vars: nil, wantNames: []string{"__SF_fn"}},
+		{name: "sorted", vars: map[string]string{"ZETA": "z", "alpha": "a", "MIDDLE": "m"}, wantNames: []string{"MIDDLE", "ZETA", "__SF_fn", "alpha"}},
+		{name: "names are verbatim", vars: map[string]string{"my-var.name": "v"}, wantNames: []string{"__SF_fn", "my-var.name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			function := &bindings.Function{Identifier: "fn", Vars: tt.vars}
+			_, metadata, err := assembleUpload(&UploadInput{Identifier: "worker", WrapperScript: []byte("x"), Functions: []*bindings.Function{function}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(metadata.Bindings))
+			for _, worker := range metadata.Bindings {
+				names = append(names, worker.Name)
+				if value, ok := tt.vars[worker.Name]; ok && (worker.Type != "plain_text" || worker.Text != value) {
+					t.Errorf("binding %s = %+v, want plain_text %q", worker.Name, worker, value)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("binding names = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestAssembleUploadDeterministic(t *testing.T) {
+	tests := []struct {
+		name  string
+		input func() *UploadInput
+	}{
+		{
+			name: "vars and bindings",
+			input: func() *UploadInput {
+				return &UploadInput{
+					Identifier:    "worker",
+					WrapperScript: []byte("export default {}"),
+					MainModule:    true,
+					Bindings: []bindings.Worker{
+						{Type: "plain_text", Name: "B", Text: "b"},
+						{Type: "kv_namespace", Name: "A", NamespaceID: "namespace"},
+					},
+					Functions: []*bindings.Function{
+						{Identifier: "two", Source: []byte("2"), Vars: map[string]string{"Y": "y", "X": "x", "W": "w"}},
+						{Identifier: "one", Source: []byte("1"), Vars: map[string]string{"V": "v", "U": "u"}},
+					},
+				}
+			},
+		},
+		{
+			name: "tags and build id",
+			input: func() *UploadInput {
+				return &UploadInput{
+					Identifier:    "worker",
+					WrapperScript: []byte("addEventListener('fetch', () => {})"),
+					Tags:          []string{"team:edge"},
+					BuildID:       "42",
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var previous []byte
+			for i := 0; i < 20; i++ {
+				_, metadata, err := assembleUpload(tt.input())
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := json.Marshal(metadata)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if previous != nil && string(data) != string(previous) {
+					t.Fatalf("metadata differs between uploads:\n%s\n%s", previous, data)
+				}
+				previous = data
+			}
+
+			_, metadata, err := assembleUpload(tt.input())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i < len(metadata.Bindings); i++ {
+				if metadata.Bindings[i-1].Name > metadata.Bindings[i].Name {
+					t.Errorf("bindings are not sorted by name: %q before %q", metadata.Bindings[i-1].Name, metadata.Bindings[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestAssembleUploadPreBundled(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   UploadInput
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "default entrypoint",
+			input: UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), PreBundled: true, CompatibilityDate: "2023-05-01"},
+			want:  `{"main_module":"worker.js","bindings":[],"compatibility_date":"2023-05-01"}`,
+		},
+		{
+			name:  "custom entrypoint",
+			input: UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), PreBundled: true, EntrypointName: "index.mjs"},
+			want:  `{"main_module":"index.mjs","bindings":[]}`,
+		},
+		{
+			name: "extra module",
+			input: UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), PreBundled: true, Modules: []bindings.Module{
+				{Name: "helper.js", Type: bindings.ModuleTypeESM, Content: []byte("export const x = 1")},
+			}},
+			wantErr: ErrPreBundledExtraParts,
+		},
+		{
+			name:    "function blob",
+			input:   UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), PreBundled: true, Functions: []*bindings.Function{{Identifier: "fn"}}},
+			wantErr: ErrPreBundledExtraParts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts, metadata, err := assembleUpload(&tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("assembleUpload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(parts) != 1 || parts[0].ContentType != "application/javascript+module" || string(parts[0].Content) != string(tt.input.WrapperScript) {
+				t.Errorf("parts = %+v, want the script as a single module part", parts)
+			}
+			data, err := json.Marshal(metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("metadata = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+// uploadedPart is a part of a worker upload as received by uploadServer
+type uploadedPart struct {
+	ContentType string
+	Content     string
+}
+
+// uploadServer accepts worker uploads, recording the metadata and parts of the
+// last one, and replies with response
+type uploadServer struct {
+	mu       sync.Mutex
+	response string
+	query    url.Values
+	metadata map[string]interface{}
+	parts    map[string]uploadedPart
+	order    []string
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "PUT" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10007,"message":"not found"}]}`))
+		return
+	}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	metadata := make(map[string]interface{})
+	parts := make(map[string]uploadedPart)
+	var order []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "metadata" {
+			if err := json.Unmarshal(content, &metadata); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			continue
+		}
+		parts[part.FormName()] = uploadedPart{ContentType: part.Header.Get("Content-Type"), Content: string(content)}
+		order = append(order, part.FormName())
+	}
+
+	s.mu.Lock()
+	s.query = r.URL.Query()
+	s.metadata = metadata
+	s.parts = parts
+	s.order = order
+	response := s.response
+	s.mu.Unlock()
+	if response == "" {
+		response = `{"success":true,"result":{}}`
+	}
+	_, _ = w.Write([]byte(response))
+}
+
+func TestUploadTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		tags     []string
+		buildID  string
+		wantTags []interface{}
+	}{
+		{name: "none"},
+		{name: "git sha", tags: []string{"git:0123abc"}, wantTags: []interface{}{"git:0123abc"}},
+		{name: "git sha and build id", tags: []string{"git:0123abc"}, buildID: "42", wantTags: []interface{}{"git:0123abc", "build_id:42"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := new(uploadServer)
+			c, _ := newTestClient(t, server, nil)
+
+			_, err := c.Upload(context.Background(), &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte("export default {}"),
+				MainModule:    true,
+				SkipSubdomain: true,
+				Tags:          tt.tags,
+				BuildID:       tt.buildID,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags, _ := server.metadata["tags"].([]interface{})
+			if !reflect.DeepEqual(tags, tt.wantTags) {
+				t.Errorf("uploaded tags = %v, want %v", tags, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestUploadTextModules(t *testing.T) {
+	tests := []struct {
+		name    string
+		modules []bindings.Module
+		want    map[string]uploadedPart
+		wantErr error
+	}{
+		{
+			name:    "html page",
+			modules: []bindings.Module{{Name: "index.html", Type: bindings.ModuleTypeText, Content: []byte("<h1>hello</h1>")}},
+			want: map[string]uploadedPart{
+				"worker.js":  {ContentType: "application/javascript+module", Content: `import page from "./index.html"; export default { fetch() { return new Response(page) } }`},
+				"index.html": {ContentType: "text/plain", Content: "<h1>hello</h1>"},
+			},
+		},
+		{
+			name: "several text files",
+			modules: []bindings.Module{
+				{Name: "index.html", Type: bindings.ModuleTypeText, Content: []byte("<h1>hello</h1>")},
+				{Name: "style.css", Type: bindings.ModuleTypeText, Content: []byte("h1 {}")},
+			},
+			want: map[string]uploadedPart{
+				"worker.js":  {ContentType: "application/javascript+module", Content: `import page from "./index.html"; export default { fetch() { return new Response(page) } }`},
+				"index.html": {ContentType: "text/plain", Content: "<h1>hello</h1>"},
+				"style.css":  {ContentType: "text/plain", Content: "h1 {}"},
+			},
+		},
+		{
+			name:    "unknown type",
+			modules: []bindings.Module{{Name: "index.html", Type: "html", Content: []byte("<h1>hello</h1>")}},
+			wantErr: bindings.ErrInvalidModuleType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := new(uploadServer)
+			c, _ := newTestClient(t, server, nil)
+
+			_, err := c.Upload(context.Background(), &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte(`import page from "./index.html"; export default { fetch() { return new Response(page) } }`),
+				MainModule:    true,
+				SkipSubdomain: true,
+				Modules:       tt.modules,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(server.parts, tt.want) {
+				t.Errorf("parts = %+v, want %+v", server.parts, tt.want)
+			}
+			if server.metadata["main_module"] != "worker.js" {
+				t.Errorf("main_module = %v, want worker.js", server.metadata["main_module"])
+			}
+		})
+	}
+}
+
+func TestUploadIncludeScript(t *testing.T) {
+	tests := []struct {
+		name              string
+		includeScript     bool
+		response          string
+		wantExcludeScript string
+		wantScript        string
+	}{
+		{
+			name:              "excluded by default",
+			response:          `{"success":true,"result":{}}`,
+			wantExcludeScript: "true",
+		},
+		{
+			name:              "included",
+			includeScript:     true,
+			response:          `{"success":true,"result":{"script":"export default {}"}}`,
+			wantExcludeScript: "false",
+			wantScript:        "export default {}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &uploadServer{response: tt.response}
+			c, _ := newTestClient(t, server, nil)
+
+			uploaded, err := c.Upload(context.Background(), &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte("export default {}"),
+				MainModule:    true,
+				SkipSubdomain: true,
+				IncludeScript: tt.includeScript,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := server.query.Get("excludeScript"); got != tt.wantExcludeScript {
+				t.Errorf("excludeScript = %q, want %q", got, tt.wantExcludeScript)
+			}
+			if uploaded.Script != tt.wantScript {
+				t.Errorf("Script = %q, want %q", uploaded.Script, tt.wantScript)
+			}
+		})
+	}
+}
+
+func TestUploadHelperModules(t *testing.T) {
+	main := `import { Buffer } from "./node/buffer.js"; export default { fetch() { return new Response(Buffer.from("ok")) } }`
+	tests := []struct {
+		name      string
+		modules   []bindings.Module
+		wantOrder []string
+		wantTypes map[string]string
+		wantErr   error
+	}{
+		{
+			name: "esm helpers",
+			modules: []bindings.Module{
+				{Name: "node/buffer.js", Type: bindings.ModuleTypeESM, Content: []byte("export const Buffer = {}")},
+				{Name: "node/events.js", Type: bindings.ModuleTypeESM, Content: []byte("export default class EventEmitter {}")},
+				{Name: "legacy.cjs", Type: bindings.ModuleTypeCommonJS, Content: []byte("module.exports = {}")},
+			},
+			wantOrder: []string{"worker.js", "node/buffer.js", "node/events.js", "legacy.cjs"},
+			wantTypes: map[string]string{
+				"worker.js":      "application/javascript+module",
+				"node/buffer.js": "application/javascript+module",
+				"node/events.js": "application/javascript+module",
+				"legacy.cjs":     "application/javascript",
+			},
+		},
+		{
+			name:    "duplicate name",
+			modules: []bindings.Module{{Name: "worker.js", Type: bindings.ModuleTypeESM}},
+			wantErr: ErrDuplicatePart,
+		},
+		{
+			name:    "missing name",
+			modules: []bindings.Module{{Type: bindings.ModuleTypeESM}},
+			wantErr: bindings.ErrModuleNameRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := new(uploadServer)
+			c, _ := newTestClient(t, server, nil)
+
+			_, err := c.Upload(context.Background(), &UploadInput{
+				Identifier:         "worker",
+				WrapperScript:      []byte(main),
+				MainModule:         true,
+				SkipSubdomain:      true,
+				CompatibilityFlags: []string{"nodejs_compat"},
+				Modules:            tt.modules,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(server.order, tt.wantOrder) {
+				t.Errorf("part order = %v, want %v", server.order, tt.wantOrder)
+			}
+			for name, contentType := range tt.wantTypes {
+				if got := server.parts[name].ContentType; got != contentType {
+					t.Errorf("part %s content type = %q, want %q", name, got, contentType)
+				}
+			}
+			if server.metadata["main_module"] != "worker.js" {
+				t.Errorf("main_module = %v, want worker.js", server.metadata["main_module"])
+			}
+			if _, ok := server.metadata["body_part"]; ok {
+				t.Errorf("metadata has body_part: %v", server.metadata)
+			}
+			if bindings, _ := server.metadata["bindings"].([]interface{}); len(bindings) != 0 {
+				t.Errorf("modules must not appear as bindings: %v", bindings)
+			}
+		})
+	}
+}
+
+func TestUploadFunctionFiles(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        []bindings.File
+		wantParts    map[string]uploadedPart
+		wantBindings map[string]string
+		wantErr      error
+	}{
+		{
+			name: "same extension with distinct bindings",
+			files: []bindings.File{
+				{Binding: "CONFIG_A", Extension: "json", ContentType: "application/json", Type: "text_blob", Content: []byte(`{"a":1}`)},
+				{Binding: "CONFIG_B", Extension: "json", ContentType: "application/json", Type: "text_blob", Content: []byte(`{"b":2}`)},
+			},
+			wantParts: map[string]uploadedPart{
+				"fn.CONFIG_A.json": {ContentType: "application/json", Content: `{"a":1}`},
+				"fn.CONFIG_B.json": {ContentType: "application/json", Content: `{"b":2}`},
+			},
+			wantBindings: map[string]string{
+				"__CONFIG_A_fn": "fn.CONFIG_A.json",
+				"__CONFIG_B_fn": "fn.CONFIG_B.json",
+			},
+		},
+		{
+			name: "same binding and extension",
+			files: []bindings.File{
+				{Binding: "CONFIG", Extension: "json", ContentType: "application/json", Type: "text_blob", Content: []byte(`{"a":1}`)},
+				{Binding: "CONFIG", Extension: "json", ContentType: "application/json", Type: "text_blob", Content: []byte(`{"b":2}`)},
+			},
+			wantErr: ErrDuplicatePart,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := new(uploadServer)
+			c, _ := newTestClient(t, server, nil)
+
+			_, err := c.Upload(context.Background(), &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte("export default {}"),
+				MainModule:    true,
+				SkipSubdomain: true,
+				Functions:     []*bindings.Function{{Identifier: "fn", Source: []byte("source"), Files: tt.files}},
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Upload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for name, want := range tt.wantParts {
+				if got, ok := server.parts[name]; !ok || got != want {
+					t.Errorf("part %s = %+v, want %+v", name, got, want)
+				}
+			}
+			got := make(map[string]string)
+			uploaded, _ := server.metadata["bindings"].([]interface{})
+			for _, binding := range uploaded {
+				binding, _ := binding.(map[string]interface{})
+				if binding["type"] == "text_blob" {
+					got[binding["name"].(string)], _ = binding["part"].(string)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantBindings) {
+				t.Errorf("file bindings = %v, want %v", got, tt.wantBindings)
+			}
+		})
+	}
+}
+
+func TestAssembleUploadObservability(t *testing.T) {
+	rate := func(r float64) *float64 { return &r }
+	tests := []struct {
+		name          string
+		observability *bindings.Observability
+		want          string
+		wantErr       error
+	}{
+		{name: "unset", want: ""},
+		{name: "enabled", observability: &bindings.Observability{Enabled: true}, want: `"observability":{"enabled":true}`},
+		{name: "sampled", observability: &bindings.Observability{Enabled: true, HeadSamplingRate: rate(0.1)}, want: `"observability":{"enabled":true,"head_sampling_rate":0.1}`},
+		{name: "no sampling", observability: &bindings.Observability{Enabled: true, HeadSamplingRate: rate(0)}, want: `"observability":{"enabled":true,"head_sampling_rate":0}`},
+		{name: "full sampling", observability: &bindings.Observability{Enabled: true, HeadSamplingRate: rate(1)}, want: `"observability":{"enabled":true,"head_sampling_rate":1}`},
+		{name: "negative rate", observability: &bindings.Observability{Enabled: true, HeadSamplingRate: rate(-0.1)}, wantErr: bindings.ErrInvalidHeadSamplingRate},
+		{name: "rate above one", observability: &bindings.Observability{Enabled: true, HeadSamplingRate: rate(1.5)}, wantErr: bindings.ErrInvalidHeadSamplingRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, metadata, err := assembleUpload(&UploadInput{Identifier: "worker", WrapperScript: []byte("x"), Observability: tt.observability})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("assembleUpload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			data, err := json.Marshal(metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if strings.Contains(string(data), "observability") {
+					t.Errorf("metadata = %s, want no observability", data)
+				}
+				return
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("metadata = %s, want it to contain %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssembleUploadMigrations(t *testing.T) {
+	tests := []struct {
+		name       string
+		migrations *bindings.Migrations
+		want       string
+		wantErr    error
+	}{
+		{
+			name:       "new class",
+			migrations: &bindings.Migrations{NewTag: "v1", NewClasses: []string{"Counter"}},
+			want:       `"migrations":{"new_tag":"v1","new_classes":["Counter"]}`,
+		},
+		{
+			name:       "renamed class",
+			migrations: &bindings.Migrations{OldTag: "v1", NewTag: "v2", RenamedClasses: []bindings.RenamedClass{{From: "Counter", To: "Tally"}}},
+			want:       `"migrations":{"old_tag":"v1","new_tag":"v2","renamed_classes":[{"from":"Counter","to":"Tally"}]}`,
+		},
+		{
+			name:       "deleted class",
+			migrations: &bindings.Migrations{OldTag: "v2", NewTag: "v3", DeletedClasses: []string{"Tally"}},
+			want:       `"migrations":{"old_tag":"v2","new_tag":"v3","deleted_classes":["Tally"]}`,
+		},
+		{
+			name:       "missing tag",
+			migrations: &bindings.Migrations{NewClasses: []string{"Counter"}},
+			wantErr:    bindings.ErrMigrationTagRequired,
+		},
+		{
+			name:       "incomplete rename",
+			migrations: &bindings.Migrations{NewTag: "v2", RenamedClasses: []bindings.RenamedClass{{From: "Counter"}}},
+			wantErr:    bindings.ErrInvalidRename,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, metadata, err := assembleUpload(&UploadInput{Identifier: "worker", WrapperScript: []byte("export default {}"), MainModule: true, Migrations: tt.migrations})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("assembleUpload() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			data, err := json.Marshal(metadata)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("metadata = %s, want it to contain %s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"strings"
+)
+
+// ValidateBindings checks that every binding has a name, that names are unique,
+// and that bindings of known types set the fields their type requires. Bindings of
+// types this package does not know about are only checked for a name.
+func ValidateBindings(workers []bindings.Worker) error {
+	var problems []string
+	seen := make(map[string]struct{}, len(workers))
+	for i, w := range workers {
+		if w.Name == "" {
+			problems = append(problems, fmt.Sprintf("binding %d (%s) has no name", i, w.Type))
+			continue
+		}
+		if _, ok := seen[w.Name]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s", ErrDuplicateBinding, w.Name))
+		}
+		seen[w.Name] = struct{}{}
+
+		var missing []string
+		switch w.Type {
+		case "":
+			missing = append(missing, "type")
+		case "secret_text":
+			if w.Text == "" {
+				missing = append(missing, "text")
+			}
+		case "kv_namespace":
+			if w.NamespaceID == "" {
+				missing = append(missing, "namespace_id")
+			}
+		case "service":
+			if w.Service == "" {
+				missing = append(missing, "service")
+			}
+		case "wasm_module", "text_blob", "data_blob":
+			if w.Part == "" {
+				missing = append(missing, "part")
+			}
+		case "ratelimit":
+			if w.NamespaceID == "" {
+				missing = append(missing, "namespace_id")
+			}
+			if w.Simple == nil {
+				missing = append(missing, "simple")
+			}
+		case "durable_object_namespace":
+			if w.ClassName == "" {
+				missing = append(missing, "class_name")
+			}
+		case "dispatch_namespace":
+			if w.Namespace == "" {
+				missing = append(missing, "namespace")
+			}
+		case "secrets_store_secret":
+			if w.StoreID == "" {
+				missing = append(missing, "store_id")
+			}
+			if w.SecretName == "" {
+				missing = append(missing, "secret_name")
+			}
+		case "hyperdrive", "d1":
+			if w.ID == "" {
+				missing = append(missing, "id")
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s binding %s is missing %s", w.Type, w.Name, strings.Join(missing, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidBinding, strings.Join(problems, "; "))
+	}
+	return nil
+}
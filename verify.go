@@ -0,0 +1,78 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// FunctionURL returns the public workers.dev URL of the worker
+func (c *Cloudflare) FunctionURL(ctx context.Context, identifier string) (string, error) {
+	subdomain, err := c.GetWorkersSubdomain(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s%s.%s.workers.dev", c.options.Prefix, identifier, subdomain), nil
+}
+
+// VerifyFunction sends req to the worker's public workers.dev URL and runs check
+// against the response. Only the path, query, method, headers and body of req are
+// used; its scheme and host are replaced. A nil req sends a GET to "/".
+func (c *Cloudflare) VerifyFunction(ctx context.Context, identifier string, req *http.Request, check func(*http.Response) error) error {
+	functionURL, err := c.FunctionURL(ctx, identifier)
+	if err != nil {
+		return err
+	}
+	u, err := url.Parse(functionURL)
+	if err != nil {
+		return fmt.Errorf("error parsing worker url: %w", err)
+	}
+
+	if req == nil {
+		req, err = http.NewRequestWithContext(ctx, "GET", functionURL+"/", nil)
+		if err != nil {
+			return fmt.Errorf("error creating verification request: %w", err)
+		}
+	} else {
+		req = req.Clone(ctx)
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.Host = ""
+	}
+
+	// the worker is probed without the API token or client certificates, which
+	// must never be sent to anything but the API
+	ctx, done, err := c.track(req)
+	if err != nil {
+		return err
+	}
+	defer done()
+	resp, err := c.probeClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error verifying worker %s: %w", identifier, err)
+	}
+	defer resp.Body.Close()
+
+	err = check(resp)
+	if err != nil {
+		return fmt.Errorf("worker %s failed verification: %w", identifier, err)
+	}
+	return nil
+}
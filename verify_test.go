@@ -0,0 +1,166 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/rs/zerolog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFunction(t *testing.T) {
+	errUnhealthy := errors.New("unhealthy")
+	tests := []struct {
+		name    string
+		req     func(t *testing.T) *http.Request
+		status  int
+		check   func(*http.Response) error
+		wantErr error
+		path    string
+		auth    string
+	}{
+		{
+			name:   "default request",
+			status: http.StatusOK,
+			check:  func(*http.Response) error { return nil },
+			path:   "/",
+		},
+		{
+			name: "custom request",
+			req: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest("GET", "http://ignored.example/health?deep=1", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return req
+			},
+			status: http.StatusOK,
+			check:  func(*http.Response) error { return nil },
+			path:   "/health",
+		},
+		{
+			name: "caller authorization is kept",
+			req: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest("GET", "http://ignored.example/", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Authorization", "Basic worker")
+				return req
+			},
+			status: http.StatusOK,
+			check:  func(*http.Response) error { return nil },
+			path:   "/",
+			auth:   "Basic worker",
+		},
+		{
+			name:   "failed check",
+			status: http.StatusInternalServerError,
+			check: func(resp *http.Response) error {
+				if resp.StatusCode != http.StatusOK {
+					return errUnhealthy
+				}
+				return nil
+			},
+			wantErr: errUnhealthy,
+			path:    "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotHost, gotPath, gotAuth string
+			worker := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHost, gotPath, gotAuth = r.Host, r.URL.Path, r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+			}))
+			defer worker.Close()
+
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/accounts/account/workers/subdomain" {
+					t.Errorf("unexpected api request to %s", r.URL.Path)
+				}
+				_, _ = w.Write([]byte(`{"success":true,"result":{"subdomain":"acct"}}`))
+			}), nil)
+
+			// every workers.dev host resolves to the test worker
+			transport := worker.Client().Transport.(*http.Transport).Clone()
+			transport.TLSClientConfig.InsecureSkipVerify = true
+			transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				return new(net.Dialer).DialContext(ctx, network, worker.Listener.Addr().String())
+			}
+			c.probeClient = &http.Client{Transport: transport}
+
+			var req *http.Request
+			if tt.req != nil {
+				req = tt.req(t)
+			}
+			err := c.VerifyFunction(context.Background(), "worker", req, tt.check)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyFunction() error = %v, want %v", err, tt.wantErr)
+			}
+			if gotHost != "worker.acct.workers.dev" {
+				t.Errorf("worker host = %q, want %q", gotHost, "worker.acct.workers.dev")
+			}
+			if gotPath != tt.path {
+				t.Errorf("worker path = %q, want %q", gotPath, tt.path)
+			}
+			if gotAuth != tt.auth {
+				t.Errorf("worker Authorization = %q, want %q", gotAuth, tt.auth)
+			}
+			if strings.Contains(gotAuth, "token") {
+				t.Errorf("api token was sent to the worker")
+			}
+		})
+	}
+}
+
+func TestVerifyFunctionAfterClose(t *testing.T) {
+	c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":true,"result":{"subdomain":"acct"}}`))
+	}), nil)
+	if _, err := c.GetWorkersSubdomain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Close()
+
+	err := c.VerifyFunction(context.Background(), "worker", nil, func(*http.Response) error { return nil })
+	if !errors.Is(err, ErrClosed) {
+		t.Fatalf("VerifyFunction() error = %v, want %v", err, ErrClosed)
+	}
+}
+
+func TestNewProbeTransportDropsClientCertificates(t *testing.T) {
+	logger := zerolog.Nop()
+	options := &Options{ClientCertificates: []tls.Certificate{{}}}
+	transport, err := newProbeTransport(options, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.TLSClientConfig != nil && len(transport.TLSClientConfig.Certificates) > 0 {
+		t.Errorf("probe transport presents %d client certificates", len(transport.TLSClientConfig.Certificates))
+	}
+	if len(options.ClientCertificates) != 1 {
+		t.Errorf("options were modified")
+	}
+}
@@ -0,0 +1,161 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/loopholelabs/cloudflare/pkg/models"
+	"strings"
+	"time"
+)
+
+// CreateVersion uploads input as a new version of the worker without deploying it,
+// annotating the version with tag and message (either may be empty). The message
+// takes precedence over input.DeployMessage.
+func (c *Cloudflare) CreateVersion(ctx context.Context, input *UploadInput, tag string, message string) (*models.Version, error) {
+	input, unlock, err := c.prepareUpload(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	parts, metadata, err := assembleUpload(input)
+	if err != nil {
+		return nil, err
+	}
+	if message == "" {
+		message = input.DeployMessage
+	}
+	if tag != "" || message != "" {
+		metadata.Annotations = &bindings.Annotations{
+			Message: message,
+			Tag:     tag,
+		}
+	} else {
+		metadata.Annotations = nil
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling metadata: %w", err)
+	}
+
+	req, err := c.newMultipartRequest(withOperation(ctx, "CreateVersion", "creating worker version"), "POST", c.scriptURL(input.Identifier)+"/versions", parts, metadataJSON)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error creating worker version: %w", err)
+	}
+	defer resp.Body.Close()
+	res := new(models.VersionResponse)
+	err = c.decodeResponse(resp, res, "creating worker version")
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Result, nil
+}
+
+type DeploymentsOptions struct {
+	// PerPage defaults to Options.PerPage and is capped at MaxPerPage
+	PerPage int
+	// Author only returns deployments made by this email address
+	Author string
+}
+
+// GetDeployments returns the worker's deployments, newest first. A nil options
+// returns every deployment.
+func (c *Cloudflare) GetDeployments(ctx context.Context, identifier string, options *DeploymentsOptions) ([]models.Deployment, error) {
+	perPage := c.options.perPage()
+	var author string
+	if options != nil {
+		if options.PerPage > 0 {
+			perPage = options.PerPage
+		}
+		if perPage > MaxPerPage {
+			perPage = MaxPerPage
+		}
+		author = options.Author
+	}
+
+	var deployments []models.Deployment
+	err := c.paginate(ctx, c.scriptURL(identifier)+"/deployments", nil, perPage, func(ctx context.Context, page int, pageURL string) (int, *models.ResultInfo, bool, error) {
+		res := new(models.DeploymentsResponse)
+		err := c.doJSON(ctx, "GET", pageURL, nil, res, "GetDeployments", "getting worker deployments")
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if wrongPage(res.ResultInfo, page) {
+			return 0, res.ResultInfo, true, nil
+		}
+
+		for _, deployment := range res.Result.Deployments {
+			if author == "" || strings.EqualFold(deployment.AuthorEmail, author) {
+				deployments = append(deployments, deployment)
+			}
+		}
+		return len(res.Result.Deployments), res.ResultInfo, false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deployments, nil
+}
+
+// WaitForDeployment polls every interval until the worker's latest deployment
+// includes versionID, or ctx is done
+func (c *Cloudflare) WaitForDeployment(ctx context.Context, identifier string, versionID string, interval time.Duration) error {
+	return WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		res := new(models.DeploymentsResponse)
+		err := c.doJSON(ctx, "GET", c.scriptURL(identifier)+"/deployments?page=1&per_page=1", nil, res, "WaitForDeployment", "getting worker deployments")
+		if err != nil {
+			return false, err
+		}
+		if len(res.Result.Deployments) == 0 {
+			return false, nil
+		}
+		for _, version := range res.Result.Deployments[0].Versions {
+			if version.VersionID == versionID {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, interval)
+}
+
+func (c *Cloudflare) DeleteVersion(ctx context.Context, identifier string, versionID string) error {
+	deployments, err := c.GetDeployments(ctx, identifier, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(deployments) > 0 {
+		for _, version := range deployments[0].Versions {
+			if version.VersionID == versionID {
+				return fmt.Errorf("%w: %s", ErrVersionDeployed, versionID)
+			}
+		}
+	}
+
+	return c.doJSON(ctx, "DELETE", c.scriptURL(identifier)+"/versions/"+versionID, nil, new(models.Response), "DeleteVersion", "deleting worker version")
+}
@@ -0,0 +1,61 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetDeployments(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     *DeploymentsOptions
+		wantPerPage string
+		wantIDs     []string
+	}{
+		{name: "default", options: nil, wantPerPage: "100", wantIDs: []string{"1", "2"}},
+		{name: "per page", options: &DeploymentsOptions{PerPage: 50}, wantPerPage: "50", wantIDs: []string{"1", "2"}},
+		{name: "per page clamped", options: &DeploymentsOptions{PerPage: 5000}, wantPerPage: "1000", wantIDs: []string{"1", "2"}},
+		{name: "author", options: &DeploymentsOptions{Author: "A@example.com"}, wantPerPage: "100", wantIDs: []string{"1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.URL.Query().Get("per_page"); got != tt.wantPerPage {
+					t.Errorf("per_page = %q, want %q", got, tt.wantPerPage)
+				}
+				_, _ = w.Write([]byte(`{"success":true,"result":{"deployments":[{"id":"1","author_email":"a@example.com"},{"id":"2","author_email":"b@example.com"}]},"result_info":{"page":1,"total_pages":1}}`))
+			}), nil)
+
+			deployments, err := c.GetDeployments(context.Background(), "worker", tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deployments) != len(tt.wantIDs) {
+				t.Fatalf("got %d deployments, want %d", len(deployments), len(tt.wantIDs))
+			}
+			for i, deployment := range deployments {
+				if deployment.ID != tt.wantIDs[i] {
+					t.Errorf("deployment %d id = %q, want %q", i, deployment.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitFor(t *testing.T) {
+	errCheck := errors.New("check failed")
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		readyAt   int
+		checkErr  error
+		timeout   time.Duration
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "ready immediately with default interval", interval: 0, readyAt: 1, wantCalls: 1},
+		{name: "negative interval", interval: -time.Second, readyAt: 1, wantCalls: 1},
+		{name: "ready after polling", interval: time.Millisecond, readyAt: 3, wantCalls: 3},
+		{name: "check error", interval: time.Millisecond, checkErr: errCheck, wantErr: errCheck, wantCalls: 1},
+		{name: "context done", interval: time.Millisecond, timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			calls := 0
+			err := WaitFor(ctx, func(context.Context) (bool, error) {
+				calls++
+				if tt.checkErr != nil {
+					return false, tt.checkErr
+				}
+				return tt.readyAt > 0 && calls >= tt.readyAt, nil
+			}, tt.interval)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitFor() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantCalls > 0 && calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWaitForDeploymentAndDomain(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		wait      func(c *Cloudflare, ctx context.Context) error
+	}{
+		{
+			name: "deployment",
+			responses: []string{
+				`{"success":true,"result":{"deployments":[{"id":"1","versions":[{"version_id":"old","percentage":100}]}]}}`,
+				`{"success":true,"result":{"deployments":[{"id":"2","versions":[{"version_id":"new","percentage":100}]}]}}`,
+			},
+			wait: func(c *Cloudflare, ctx context.Context) error {
+				return c.WaitForDeployment(ctx, "worker", "new", time.Millisecond)
+			},
+		},
+		{
+			name: "domain",
+			responses: []string{
+				`{"success":true,"result":[]}`,
+				`{"success":true,"result":[{"hostname":"other.example.com"}]}`,
+				`{"success":true,"result":[{"hostname":"other.example.com"},{"hostname":"app.example.com"}]}`,
+			},
+			wait: func(c *Cloudflare, ctx context.Context) error {
+				return c.WaitForDomain(ctx, "worker", "app.example.com", time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int64
+			c, _ := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt64(&requests, 1))
+				if n > len(tt.responses) {
+					n = len(tt.responses)
+				}
+				_, _ = w.Write([]byte(tt.responses[n-1]))
+			}), nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tt.wait(c, ctx); err != nil {
+				t.Fatal(err)
+			}
+			if int(requests) != len(tt.responses) {
+				t.Errorf("requests = %d, want %d", requests, len(tt.responses))
+			}
+		})
+	}
+}
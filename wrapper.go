@@ -0,0 +1,124 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// wrapperReferencePattern matches __SF_ references using the characters allowed
+// in worker names, so that hyphenated identifiers and UUIDs are matched whole
+var wrapperReferencePattern = regexp.MustCompile(`__SF_([A-Za-z0-9_-]+)`)
+
+// DefaultWrapperTemplate routes requests to a function using the first segment of the
+// request path, and calls handle with the function's __SF_ data blob. The blobs are
+// read through globalThis, since identifiers such as "my-worker" are not valid
+// JavaScript identifiers. The default handle responds with 501, so callers that
+// execute functions in the wrapper should provide their own template.
+const DefaultWrapperTemplate = `const functions = {
+{{- range . }}
+  {{ printf "%q" .Identifier }}: globalThis[{{ printf "%q" (print "__SF_" .Identifier) }}],
+{{- end }}
+};
+
+{{ block "handle" . -}}
+async function handle(request, identifier, source) {
+  return new Response("no handler configured for function " + identifier, { status: 501 });
+}
+{{- end }}
+
+addEventListener("fetch", (event) => {
+  const identifier = new URL(event.request.url).pathname.split("/")[1];
+  const source = functions[identifier];
+  if (source === undefined) {
+    event.respondWith(new Response("function not found", { status: 404 }));
+    return;
+  }
+  event.respondWith(handle(event.request, identifier, source));
+});
+`
+
+// GenerateWrapper executes tmpl (or DefaultWrapperTemplate if tmpl is empty) against
+// functions to produce a wrapper script for UploadFunction
+func GenerateWrapper(functions []*bindings.Function, tmpl string) ([]byte, error) {
+	if tmpl == "" {
+		tmpl = DefaultWrapperTemplate
+	}
+
+	t, err := template.New("wrapper").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing wrapper template: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = t.Execute(buf, functions)
+	if err != nil {
+		return nil, fmt.Errorf("error executing wrapper template: %w", err)
+	}
+
+	if len(bytes.TrimSpace(buf.Bytes())) == 0 {
+		return nil, ErrEmptyWrapper
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ValidateWrapper checks that every __SF_ data blob the wrapper script references
+// belongs to one of functions, returning ErrStaleWrapper with the identifiers that
+// don't. It only scans the script text, so references built at runtime are missed
+// and references in comments are included.
+func ValidateWrapper(wrapper []byte, functions []*bindings.Function) error {
+	identifiers := make(map[string]struct{}, len(functions))
+	for _, function := range functions {
+		identifiers[function.Identifier] = struct{}{}
+	}
+
+	missing := make(map[string]struct{})
+	for _, match := range wrapperReferencePattern.FindAllSubmatch(wrapper, -1) {
+		if _, ok := identifiers[string(match[1])]; !ok {
+			missing[string(match[1])] = struct{}{}
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	stale := make([]string, 0, len(missing))
+	for identifier := range missing {
+		stale = append(stale, identifier)
+	}
+	sort.Strings(stale)
+	return fmt.Errorf("%w: %s", ErrStaleWrapper, strings.Join(stale, ", "))
+}
+
+// checkWrapper validates the wrapper script of an upload, only failing the upload
+// when Options.StrictWrapperReferences is set
+func (c *Cloudflare) checkWrapper(ctx context.Context, input *UploadInput) error {
+	err := ValidateWrapper(input.WrapperScript, input.Functions)
+	if err == nil || c.options.StrictWrapperReferences {
+		return err
+	}
+	c.log(ctx).Warn().Err(c.redactErr(err)).Str("identifier", input.Identifier).Msg("wrapper script references functions that are not uploaded")
+	return nil
+}
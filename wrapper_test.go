@@ -0,0 +1,206 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/loopholelabs/cloudflare/pkg/bindings"
+	"github.com/rs/zerolog"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWrapper(t *testing.T) {
+	tests := []struct {
+		name        string
+		identifiers []string
+		tmpl        string
+		want        []string
+		wantErr     error
+	}{
+		{
+			name:        "plain identifiers",
+			identifiers: []string{"hello"},
+			want:        []string{`"hello": globalThis["__SF_hello"],`},
+		},
+		{
+			name:        "hyphenated and uuid identifiers",
+			identifiers: []string{"my-worker", "0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b"},
+			want: []string{
+				`"my-worker": globalThis["__SF_my-worker"],`,
+				`"0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b": globalThis["__SF_0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b"],`,
+			},
+		},
+		{
+			name:        "quotes are escaped",
+			identifiers: []string{`a"b`},
+			want:        []string{`"a\"b": globalThis["__SF_a\"b"],`},
+		},
+		{
+			name:    "empty template output",
+			tmpl:    "{{ range . }}{{ end }}",
+			wantErr: ErrEmptyWrapper,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			functions := make([]*bindings.Function, 0, len(tt.identifiers))
+			for _, identifier := range tt.identifiers {
+				functions = append(functions, &bindings.Function{Identifier: identifier})
+			}
+			wrapper, err := GenerateWrapper(functions, tt.tmpl)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateWrapper() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(wrapper), want) {
+					t.Errorf("wrapper does not contain %s:\n%s", want, wrapper)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateWrapper(t *testing.T) {
+	tests := []struct {
+		name        string
+		wrapper     string
+		identifiers []string
+		wantErr     error
+		wantStale   string
+	}{
+		{
+			name:        "bare references",
+			wrapper:     "const a = __SF_hello; const b = __SF_world_2;",
+			identifiers: []string{"hello", "world_2"},
+		},
+		{
+			name:        "quoted hyphenated references",
+			wrapper:     `globalThis["__SF_my-worker"]; globalThis["__SF_0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b"]`,
+			identifiers: []string{"my-worker", "0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b"},
+		},
+		{
+			name:        "hyphenated prefix of an uploaded function",
+			wrapper:     `globalThis["__SF_my-worker-old"]`,
+			identifiers: []string{"my-worker"},
+			wantErr:     ErrStaleWrapper,
+			wantStale:   "my-worker-old",
+		},
+		{
+			name:        "no references",
+			wrapper:     "export default {}",
+			identifiers: []string{"hello"},
+		},
+		{
+			name:        "several stale references are sorted",
+			wrapper:     "__SF_zeta; __SF_hello; __SF_alpha; __SF_zeta",
+			identifiers: []string{"hello"},
+			wantErr:     ErrStaleWrapper,
+			wantStale:   "alpha, zeta",
+		},
+		{
+			name:        "missing uuid",
+			wrapper:     `globalThis["__SF_0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b"]`,
+			identifiers: []string{"0b5f6a8e"},
+			wantErr:     ErrStaleWrapper,
+			wantStale:   "0b5f6a8e-3c1d-4f7a-9e2b-6d8c4a1f0e3b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			functions := make([]*bindings.Function, 0, len(tt.identifiers))
+			for _, identifier := range tt.identifiers {
+				functions = append(functions, &bindings.Function{Identifier: identifier})
+			}
+			err := ValidateWrapper([]byte(tt.wrapper), functions)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateWrapper() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.HasSuffix(err.Error(), ": "+tt.wantStale) {
+				t.Errorf("ValidateWrapper() error = %v, want stale %s", err, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestCheckWrapperRedactsLog(t *testing.T) {
+	out := new(bytes.Buffer)
+	logger := zerolog.New(out)
+	c, err := New(&Options{UserID: "account1234", Token: "token", RedactAccountID: true}, &logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	input := &UploadInput{
+		Identifier:    "wrapper",
+		WrapperScript: []byte(`globalThis["__SF_account1234-old"]`),
+	}
+	if err := c.checkWrapper(context.Background(), input); err != nil {
+		t.Fatalf("checkWrapper() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "*******1234-old") {
+		t.Errorf("log does not contain the redacted reference: %s", out)
+	}
+	if strings.Contains(out.String(), "account1234") {
+		t.Errorf("log contains the account id: %s", out)
+	}
+}
+
+func TestCheckWrapper(t *testing.T) {
+	tests := []struct {
+		name    string
+		strict  bool
+		wrapper string
+		wantErr error
+		wantLog bool
+	}{
+		{name: "matching", wrapper: `globalThis["__SF_fn"]`},
+		{name: "matching strict", strict: true, wrapper: `globalThis["__SF_fn"]`},
+		{name: "mismatched warns", wrapper: `globalThis["__SF_removed"]`, wantLog: true},
+		{name: "mismatched strict fails", strict: true, wrapper: `globalThis["__SF_removed"]`, wantErr: ErrStaleWrapper},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			logger := zerolog.New(out)
+			c, err := New(&Options{UserID: "account", Token: "token", StrictWrapperReferences: tt.strict}, &logger)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			input := &UploadInput{
+				Identifier:    "worker",
+				WrapperScript: []byte(tt.wrapper),
+				Functions:     []*bindings.Function{{Identifier: "fn"}},
+			}
+			err = c.checkWrapper(context.Background(), input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("checkWrapper() error = %v, want %v", err, tt.wantErr)
+			}
+			if logged := strings.Contains(out.String(), "removed"); logged != tt.wantLog {
+				t.Errorf("logged = %t, want %t: %s", logged, tt.wantLog, out)
+			}
+		})
+	}
+}